@@ -0,0 +1,219 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"walk/errs"
+)
+
+// archiveFileSystem is a read-only FileSystem whose entire contents are
+// extracted into an in-memory tree up front. ZipFileSystem, TarFileSystem,
+// TarGzFileSystem and TarBz2FileSystem all build on it, differing only in
+// how they decode their archive format.
+type archiveFileSystem struct {
+	*MemoryFileSystem
+}
+
+func newArchiveFileSystem() *archiveFileSystem {
+	return &archiveFileSystem{NewMemoryFileSystem()}
+}
+
+func (fs *archiveFileSystem) putFile(name string, r io.Reader) error {
+	dir, _ := splitDirBase(name)
+	if dir != "" {
+		if err := fs.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return errs.Wrap(errs.ErrIO, err)
+	}
+
+	// Write directly through the embedded MemoryFileSystem, bypassing
+	// archiveFileSystem.OpenFile's read-only enforcement below: putFile
+	// is how ZipFileSystem/TarFileSystem etc. populate the in-memory
+	// tree while extracting the archive, before it's handed to a caller.
+	f, werr := fs.MemoryFileSystem.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if werr != nil {
+		return werr
+	}
+	defer f.Close()
+
+	if _, werr := f.Write(data); werr != nil {
+		return werr
+	}
+
+	return nil
+}
+
+// OpenFile rejects any flag that could modify the archive's contents, and
+// Remove is rejected as well. MkdirAll is not overridden: it still creates
+// directories in the in-memory tree. CopyOnWriteFileSystem is what callers
+// should mount over it to get a writable view.
+func (fs *archiveFileSystem) OpenFile(name string, flag int, perm uint32) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE) != 0 {
+		return nil, errs.Wrap(errs.ErrPermission, errors.New("archive filesystem is read-only"))
+	}
+	return fs.MemoryFileSystem.OpenFile(name, os.O_RDONLY, perm)
+}
+
+func (fs *archiveFileSystem) Remove(name string) error {
+	return errs.Wrap(errs.ErrPermission, errors.New("archive filesystem is read-only"))
+}
+
+func splitDirBase(name string) (dir, base string) {
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '/' {
+			return name[:i], name[i+1:]
+		}
+	}
+	return "", name
+}
+
+// ZipFileSystem is a read-only FileSystem over the contents of a zip
+// archive.
+type ZipFileSystem struct {
+	*archiveFileSystem
+}
+
+func NewZipFileSystem(archivePath string) (*ZipFileSystem, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrIO, err)
+	}
+	defer r.Close()
+
+	fs := newArchiveFileSystem()
+
+	for _, zf := range r.File {
+		if zf.FileInfo().IsDir() {
+			if err := fs.MkdirAll(zf.Name, 0755); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, errs.Wrap(errs.ErrIO, err)
+		}
+
+		err2 := fs.putFile(zf.Name, rc)
+		rc.Close()
+		if err2 != nil {
+			return nil, err2
+		}
+	}
+
+	return &ZipFileSystem{fs}, nil
+}
+
+// TarFileSystem is a read-only FileSystem over the contents of an
+// uncompressed tar archive.
+type TarFileSystem struct {
+	*archiveFileSystem
+}
+
+func NewTarFileSystem(archivePath string) (*TarFileSystem, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, classifyOSError(err)
+	}
+	defer f.Close()
+
+	fs, err := readTar(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TarFileSystem{fs}, nil
+}
+
+// TarGzFileSystem is a read-only FileSystem over the contents of a
+// gzip-compressed tar archive (.tar.gz / .tgz).
+type TarGzFileSystem struct {
+	*archiveFileSystem
+}
+
+func NewTarGzFileSystem(archivePath string) (*TarGzFileSystem, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, classifyOSError(err)
+	}
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrInvalidFormat, err)
+	}
+	defer gzr.Close()
+
+	fs, err := readTar(gzr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TarGzFileSystem{fs}, nil
+}
+
+// TarBz2FileSystem is a read-only FileSystem over the contents of a
+// bzip2-compressed tar archive (.tar.bz2 / .tbz2).
+type TarBz2FileSystem struct {
+	*archiveFileSystem
+}
+
+func NewTarBz2FileSystem(archivePath string) (*TarBz2FileSystem, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, classifyOSError(err)
+	}
+	defer f.Close()
+
+	fs, err := readTar(bzip2.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TarBz2FileSystem{fs}, nil
+}
+
+func readTar(r io.Reader) (*archiveFileSystem, error) {
+	fs := newArchiveFileSystem()
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errs.Wrap(errs.ErrInvalidFormat, err)
+		}
+
+		if hdr.FileInfo().IsDir() {
+			if err := fs.MkdirAll(hdr.Name, 0755); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := fs.putFile(hdr.Name, tr); err != nil {
+			return nil, err
+		}
+	}
+
+	return fs, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"walk/errs"
+)
+
+func writeTestZip(t *testing.T, entries map[string]string) string {
+	t.Helper()
+
+	archivePath := filepath.Join(t.TempDir(), "test.zip")
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip Create(%s): %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("zip Write(%s): %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip Close: %v", err)
+	}
+
+	return archivePath
+}
+
+func TestZipFileSystemReadsContents(t *testing.T) {
+	archivePath := writeTestZip(t, map[string]string{"existing.txt": "hello"})
+
+	fs, err := NewZipFileSystem(archivePath)
+	if err != nil {
+		t.Fatalf("NewZipFileSystem: %v", err)
+	}
+
+	f, err := fs.Open("existing.txt")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("read %q, want %q", data, "hello")
+	}
+}
+
+func TestZipFileSystemRejectsWrites(t *testing.T) {
+	archivePath := writeTestZip(t, map[string]string{"existing.txt": "hello"})
+
+	fs, err := NewZipFileSystem(archivePath)
+	if err != nil {
+		t.Fatalf("NewZipFileSystem: %v", err)
+	}
+
+	_, err = fs.OpenFile("existing.txt", os.O_WRONLY|os.O_TRUNC, 0644)
+	if !errs.IsPermission(err) {
+		t.Errorf("OpenFile(O_WRONLY|O_TRUNC) = %v, want errs.ErrPermission", err)
+	}
+
+	_, err = fs.OpenFile("new.txt", os.O_CREATE|os.O_WRONLY, 0644)
+	if !errs.IsPermission(err) {
+		t.Errorf("OpenFile(O_CREATE|O_WRONLY) = %v, want errs.ErrPermission", err)
+	}
+
+	if err := fs.Remove("existing.txt"); !errs.IsPermission(err) {
+		t.Errorf("Remove = %v, want errs.ErrPermission", err)
+	}
+
+	// Content must be untouched by the rejected write attempts.
+	f, err := fs.Open("existing.txt")
+	if err != nil {
+		t.Fatalf("Open after rejected writes: %v", err)
+	}
+	defer f.Close()
+
+	data, _ := io.ReadAll(f)
+	if string(data) != "hello" {
+		t.Errorf("content after rejected writes = %q, want %q", data, "hello")
+	}
+}
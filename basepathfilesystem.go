@@ -0,0 +1,81 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"walk/errs"
+)
+
+// BasePathFileSystem chroots another FileSystem to a base path, rejecting
+// any name that would escape it via "..". It lets callers mount a
+// sub-directory (or an archive root) as if it were the whole FileSystem.
+type BasePathFileSystem struct {
+	inner    FileSystem
+	basePath string
+}
+
+func NewBasePathFileSystem(inner FileSystem, basePath string) *BasePathFileSystem {
+	return &BasePathFileSystem{inner: inner, basePath: basePath}
+}
+
+func (fs *BasePathFileSystem) resolve(name string) (string, error) {
+	if rel := path.Clean(name); rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", errs.Wrap(errs.ErrPermission, errors.New("path escapes base path: "+name))
+	}
+
+	return path.Join(fs.basePath, path.Clean("/"+name)), nil
+}
+
+func (fs *BasePathFileSystem) Open(name string) (File, error) {
+	real, err := fs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.inner.Open(real)
+}
+
+func (fs *BasePathFileSystem) OpenFile(name string, flag int, perm uint32) (File, error) {
+	real, err := fs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.inner.OpenFile(real, flag, perm)
+}
+
+func (fs *BasePathFileSystem) Stat(name string) (FileInfo, error) {
+	real, err := fs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.inner.Stat(real)
+}
+
+func (fs *BasePathFileSystem) ReadDir(name string) ([]FileInfo, error) {
+	real, err := fs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.inner.ReadDir(real)
+}
+
+func (fs *BasePathFileSystem) MkdirAll(p string, perm uint32) error {
+	real, err := fs.resolve(p)
+	if err != nil {
+		return err
+	}
+	return fs.inner.MkdirAll(real, perm)
+}
+
+func (fs *BasePathFileSystem) Remove(name string) error {
+	real, err := fs.resolve(name)
+	if err != nil {
+		return err
+	}
+	return fs.inner.Remove(real)
+}
@@ -0,0 +1,81 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import "os"
+
+// CopyOnWriteFileSystem layers a writable FileSystem over a read-only one:
+// reads fall through to the lower layer unless the upper layer already has
+// the entry, and every write goes to the upper layer only. It lets callers
+// mount something like a ZipFileSystem read-only while still persisting
+// files such as settings.ini locally.
+type CopyOnWriteFileSystem struct {
+	upper FileSystem
+	lower FileSystem
+}
+
+func NewCopyOnWriteFileSystem(upper, lower FileSystem) *CopyOnWriteFileSystem {
+	return &CopyOnWriteFileSystem{upper: upper, lower: lower}
+}
+
+func (fs *CopyOnWriteFileSystem) Open(name string) (File, error) {
+	if f, err := fs.upper.Open(name); err == nil {
+		return f, nil
+	}
+
+	return fs.lower.Open(name)
+}
+
+func (fs *CopyOnWriteFileSystem) OpenFile(name string, flag int, perm uint32) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE) != 0 {
+		return fs.upper.OpenFile(name, flag, perm)
+	}
+
+	if f, err := fs.upper.OpenFile(name, flag, perm); err == nil {
+		return f, nil
+	}
+
+	return fs.lower.OpenFile(name, flag, perm)
+}
+
+func (fs *CopyOnWriteFileSystem) Stat(name string) (FileInfo, error) {
+	if fi, err := fs.upper.Stat(name); err == nil {
+		return fi, nil
+	}
+
+	return fs.lower.Stat(name)
+}
+
+func (fs *CopyOnWriteFileSystem) ReadDir(name string) ([]FileInfo, error) {
+	upperEntries, upperErr := fs.upper.ReadDir(name)
+	lowerEntries, lowerErr := fs.lower.ReadDir(name)
+
+	if upperErr != nil && lowerErr != nil {
+		return nil, lowerErr
+	}
+
+	seen := make(map[string]bool)
+	merged := make([]FileInfo, 0, len(upperEntries)+len(lowerEntries))
+
+	for _, fi := range upperEntries {
+		seen[fi.Name()] = true
+		merged = append(merged, fi)
+	}
+	for _, fi := range lowerEntries {
+		if !seen[fi.Name()] {
+			merged = append(merged, fi)
+		}
+	}
+
+	return merged, nil
+}
+
+func (fs *CopyOnWriteFileSystem) MkdirAll(path string, perm uint32) error {
+	return fs.upper.MkdirAll(path, perm)
+}
+
+func (fs *CopyOnWriteFileSystem) Remove(name string) error {
+	return fs.upper.Remove(name)
+}
@@ -0,0 +1,359 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"walk/errs"
+)
+
+// DirWalkerErrorKind classifies the errors a DirWalker can report while
+// scanning, so a tree view can render a badge on the offending node for a
+// transient failure instead of popping up a modal dialog.
+type DirWalkerErrorKind int
+
+const (
+	DirWalkerErrorNotDirectory DirWalkerErrorKind = iota
+	DirWalkerErrorPermission
+	DirWalkerErrorIO
+)
+
+// DirWalkerError wraps an error encountered while scanning a single path,
+// together with the path and a DirWalkerErrorKind classifying it.
+type DirWalkerError struct {
+	Path string
+	Kind DirWalkerErrorKind
+	Err  error
+}
+
+func (e *DirWalkerError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+// Filter decides whether path (with the given FileInfo) should be included
+// in a walk. It replaces the previously hardcoded excludePath check in the
+// file browser example.
+type Filter func(path string, fi FileInfo) bool
+
+// DirWalkerProgress reports how far a walk has gotten: how many
+// directories have been scanned so far and how many bytes of file content
+// have been accounted for.
+type DirWalkerProgress struct {
+	DirsScanned    int
+	BytesAccounted int64
+}
+
+// DirWalkerEntry is a single file or directory discovered by a DirWalker,
+// streamed to the caller as it is found.
+type DirWalkerEntry struct {
+	Path string
+	Info FileInfo
+}
+
+// DirWalkerProgressEvent lets callers observe a DirWalker's progress,
+// following the same Attach/Detach pattern as walk's other Event types.
+type DirWalkerProgressEvent struct {
+	handlers map[int]func(progress DirWalkerProgress)
+	nextID   int
+}
+
+func (e *DirWalkerProgressEvent) Attach(handler func(progress DirWalkerProgress)) int {
+	if e.handlers == nil {
+		e.handlers = make(map[int]func(progress DirWalkerProgress))
+	}
+
+	e.nextID++
+	e.handlers[e.nextID] = handler
+
+	return e.nextID
+}
+
+func (e *DirWalkerProgressEvent) Detach(handle int) {
+	delete(e.handlers, handle)
+}
+
+type DirWalkerProgressEventPublisher struct {
+	event DirWalkerProgressEvent
+}
+
+func (p *DirWalkerProgressEventPublisher) Event() *DirWalkerProgressEvent {
+	return &p.event
+}
+
+func (p *DirWalkerProgressEventPublisher) Publish(progress DirWalkerProgress) {
+	for _, handler := range p.event.handlers {
+		if handler != nil {
+			handler(progress)
+		}
+	}
+}
+
+// pathQueue is an unbounded FIFO of directory paths still to be scanned,
+// together with a count of paths that are queued or currently being
+// scanned by a worker. Unlike a fixed-size channel, push never blocks, so
+// a worker that discovers more subdirectories than the pool has capacity
+// to buffer can't deadlock every other worker trying to do the same.
+// pending reaching zero is how the queue knows the walk is finished:
+// there's nothing left queued and nothing still being scanned that might
+// push more paths.
+type pathQueue struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	paths   []string
+	pending int
+	closed  bool
+}
+
+func newPathQueue() *pathQueue {
+	q := &pathQueue{}
+	q.cond = sync.NewCond(&q.mutex)
+	return q
+}
+
+// push adds path to the queue, counting it as pending until a worker
+// calls done for it.
+func (q *pathQueue) push(path string) {
+	q.mutex.Lock()
+	q.paths = append(q.paths, path)
+	q.pending++
+	q.cond.Signal()
+	q.mutex.Unlock()
+}
+
+// pop blocks until a path is available or the queue is closed, returning
+// ok=false in the latter case. The queue closes itself once pending
+// drops to zero (see done) or watchCancel observes ctx done.
+func (q *pathQueue) pop() (path string, ok bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.paths) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return "", false
+	}
+
+	path, q.paths = q.paths[0], q.paths[1:]
+	return path, true
+}
+
+// done marks one path previously returned by pop as fully scanned,
+// closing the queue once nothing is queued or still being scanned.
+func (q *pathQueue) done() {
+	q.mutex.Lock()
+	q.pending--
+	if q.pending == 0 {
+		q.closed = true
+		q.cond.Broadcast()
+	}
+	q.mutex.Unlock()
+}
+
+// watchCancel closes the queue as soon as ctx is done, waking any worker
+// blocked in pop so it can exit instead of waiting on a walk that's been
+// cancelled.
+func (q *pathQueue) watchCancel(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		q.mutex.Lock()
+		q.closed = true
+		q.cond.Broadcast()
+		q.mutex.Unlock()
+	}()
+}
+
+// syncFuncQueue collects funcs posted from background goroutines so they
+// can be drained and run on the GUI thread in a single batch, instead of
+// each entry triggering its own cross-thread call.
+type syncFuncQueue struct {
+	mutex sync.Mutex
+	funcs []func()
+}
+
+func (q *syncFuncQueue) push(f func()) {
+	q.mutex.Lock()
+	q.funcs = append(q.funcs, f)
+	q.mutex.Unlock()
+}
+
+// drain removes and returns every queued func, leaving the queue empty.
+func (q *syncFuncQueue) drain() []func() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	funcs := q.funcs
+	q.funcs = nil
+
+	return funcs
+}
+
+// DirWalker walks a directory tree asynchronously, fanning reads out
+// across a worker pool and streaming results back through EntryFound so
+// widgets like TreeView and TableView stay responsive on large or slow
+// (e.g. network-backed) trees.
+type DirWalker struct {
+	fs      FileSystem
+	workers int
+
+	Filter Filter
+
+	// EntryFound is called, via SyncFunc, for every discovered entry.
+	EntryFound func(DirWalkerEntry)
+
+	// EntryFailed is called, via SyncFunc, for every path that could not
+	// be read, together with a classified DirWalkerError.
+	EntryFailed func(*DirWalkerError)
+
+	progressPublisher DirWalkerProgressEventPublisher
+
+	queue  syncFuncQueue
+	cancel context.CancelFunc
+
+	mutex   sync.Mutex
+	scanned int
+	bytes   int64
+}
+
+// NewDirWalker creates a DirWalker reading through fs with the given
+// number of concurrent workers. A workers value <= 0 defaults to 4.
+func NewDirWalker(fs FileSystem, workers int) *DirWalker {
+	if workers <= 0 {
+		workers = 4
+	}
+
+	return &DirWalker{fs: fs, workers: workers}
+}
+
+func (w *DirWalker) Progress() *DirWalkerProgressEvent {
+	return w.progressPublisher.Event()
+}
+
+// Start begins walking root in the background. It returns immediately;
+// results are delivered through EntryFound/EntryFailed via SyncFunc, and
+// SyncFunc must be pumped by the caller (typically from the GUI's idle or
+// timer handling) to actually run them on the GUI thread.
+func (w *DirWalker) Start(ctx context.Context, root string) {
+	ctx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+
+	paths := newPathQueue()
+	paths.watchCancel(ctx)
+	paths.push(root)
+
+	for i := 0; i < w.workers; i++ {
+		go w.worker(ctx, paths)
+	}
+}
+
+// Cancel stops the walk. Workers already in flight finish their current
+// directory read before observing cancellation.
+func (w *DirWalker) Cancel() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+}
+
+// SyncFunc drains and runs every func posted by background workers since
+// the last call. Call it from the GUI thread, e.g. on a timer, to apply
+// batched EntryFound/EntryFailed/Progress callbacks without blocking the
+// workers on a cross-thread call per entry.
+func (w *DirWalker) SyncFunc() {
+	for _, f := range w.queue.drain() {
+		f()
+	}
+}
+
+func (w *DirWalker) worker(ctx context.Context, paths *pathQueue) {
+	for {
+		dirPath, ok := paths.pop()
+		if !ok {
+			return
+		}
+		w.scanDir(ctx, dirPath, paths)
+		paths.done()
+	}
+}
+
+func (w *DirWalker) scanDir(ctx context.Context, dirPath string, paths *pathQueue) {
+	entries, err := w.fs.ReadDir(dirPath)
+	if err != nil {
+		w.reportError(dirPath, err)
+		return
+	}
+
+	w.mutex.Lock()
+	w.scanned++
+	scanned := w.scanned
+	w.mutex.Unlock()
+
+	for _, fi := range entries {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		fullPath := joinPath(dirPath, fi.Name())
+
+		if w.Filter != nil && !w.Filter(fullPath, fi) {
+			continue
+		}
+
+		if !fi.IsDir() {
+			w.mutex.Lock()
+			w.bytes += fi.Size()
+			bytes := w.bytes
+			w.mutex.Unlock()
+			w.queue.push(func() { w.progressPublisher.Publish(DirWalkerProgress{scanned, bytes}) })
+		}
+
+		entry := DirWalkerEntry{Path: fullPath, Info: fi}
+		if w.EntryFound != nil {
+			w.queue.push(func() { w.EntryFound(entry) })
+		}
+
+		if fi.IsDir() {
+			paths.push(fullPath)
+		}
+	}
+
+	w.mutex.Lock()
+	bytes := w.bytes
+	w.mutex.Unlock()
+	w.queue.push(func() { w.progressPublisher.Publish(DirWalkerProgress{scanned, bytes}) })
+}
+
+func (w *DirWalker) reportError(dirPath string, err error) {
+	walkErr := &DirWalkerError{Path: dirPath, Kind: classifyDirWalkerError(err), Err: err}
+
+	if w.EntryFailed != nil {
+		w.queue.push(func() { w.EntryFailed(walkErr) })
+	}
+}
+
+func classifyDirWalkerError(err error) DirWalkerErrorKind {
+	switch {
+	case errs.IsNotDir(err):
+		return DirWalkerErrorNotDirectory
+	case errs.IsPermission(err):
+		return DirWalkerErrorPermission
+	default:
+		return DirWalkerErrorIO
+	}
+}
+
+func joinPath(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	if dir[len(dir)-1] == '/' || dir[len(dir)-1] == '\\' {
+		return dir + name
+	}
+	return dir + "/" + name
+}
@@ -0,0 +1,152 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// drainUntil repeatedly calls w.SyncFunc, giving queued EntryFound/Progress
+// callbacks a chance to run, until cond reports done or the deadline passes.
+func drainUntil(t *testing.T, w *DirWalker, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w.SyncFunc()
+		if cond() {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for DirWalker to finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDirWalkerFindsEntriesAndAppliesFilter(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	fs.MkdirAll("root/sub", 0755)
+	fs.MkdirAll("root/skip", 0755)
+	for _, name := range []string{"root/a.txt", "root/sub/b.txt", "root/skip/c.txt"} {
+		f, err := fs.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatalf("OpenFile(%s): %v", name, err)
+		}
+		f.Write([]byte("x"))
+		f.Close()
+	}
+
+	w := NewDirWalker(fs, 2)
+	w.Filter = func(path string, fi FileInfo) bool {
+		return fi.Name() != "skip"
+	}
+
+	var mu sync.Mutex
+	found := make(map[string]bool)
+	w.EntryFound = func(entry DirWalkerEntry) {
+		mu.Lock()
+		found[entry.Path] = true
+		mu.Unlock()
+	}
+
+	w.Start(context.Background(), "root")
+
+	want := []string{"root/a.txt", "root/sub", "root/sub/b.txt"}
+	drainUntil(t, w, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range want {
+			if !found[p] {
+				return false
+			}
+		}
+		return true
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if found["root/skip"] || found["root/skip/c.txt"] {
+		t.Errorf("Filter should have excluded root/skip entirely, found = %v", found)
+	}
+}
+
+// TestDirWalkerWideFanoutDoesNotDeadlock walks a directory with many more
+// immediate subdirectories than the worker pool has slots for. A fixed-size
+// paths channel that both workers drain and blocking-send back into can
+// deadlock here: every worker ends up blocked trying to push a subdirectory
+// with nothing left to receive. This is expected to complete well within
+// the drainUntil deadline.
+func TestDirWalkerWideFanoutDoesNotDeadlock(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	const numDirs = 50
+	for i := 0; i < numDirs; i++ {
+		fs.MkdirAll(fmt.Sprintf("root/d%d", i), 0755)
+	}
+
+	w := NewDirWalker(fs, 2)
+
+	var mu sync.Mutex
+	var dirsFound int
+	w.EntryFound = func(entry DirWalkerEntry) {
+		mu.Lock()
+		dirsFound++
+		mu.Unlock()
+	}
+
+	w.Start(context.Background(), "root")
+
+	drainUntil(t, w, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return dirsFound == numDirs
+	})
+}
+
+func TestDirWalkerReportsProgress(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	fs.MkdirAll("root", 0755)
+	f, _ := fs.OpenFile("root/a.txt", os.O_CREATE|os.O_WRONLY, 0644)
+	f.Write([]byte("hello"))
+	f.Close()
+
+	w := NewDirWalker(fs, 1)
+
+	var mu sync.Mutex
+	var last DirWalkerProgress
+	w.Progress().Attach(func(p DirWalkerProgress) {
+		mu.Lock()
+		last = p
+		mu.Unlock()
+	})
+
+	w.Start(context.Background(), "root")
+
+	drainUntil(t, w, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return last.BytesAccounted == 5
+	})
+}
+
+func TestDirWalkerCancel(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	fs.MkdirAll("root/sub", 0755)
+
+	w := NewDirWalker(fs, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w.Start(ctx, "root")
+	w.Cancel()
+	cancel()
+
+	// Cancelling must not panic or hang subsequent SyncFunc calls.
+	drainUntil(t, w, func() bool { return true })
+}
@@ -0,0 +1,35 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"errors"
+	"fmt"
+
+	"walk/errs"
+)
+
+// ErrorDialog shows err in a MsgBox, same as a plain showError would, but
+// appends the captured stack trace when err (or something it wraps) is a
+// *errs.Error, so a debug build doesn't have to reproduce a transient
+// failure just to see where it came from.
+func ErrorDialog(owner Form, title string, err error) {
+	if err == nil {
+		return
+	}
+
+	text := err.Error()
+
+	var stackErr *errs.Error
+	errors.As(err, &stackErr)
+
+	if stackErr != nil {
+		for _, frame := range stackErr.StackTrace() {
+			text += fmt.Sprintf("\n\t%s\n\t\t%s:%d", frame.Func, frame.File, frame.Line)
+		}
+	}
+
+	MsgBox(owner, title, text, MsgBoxOK|MsgBoxIconError)
+}
@@ -0,0 +1,104 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package errs provides the typed, stack-trace-carrying errors used
+// throughout walk, so callers can branch on what went wrong (file not
+// found vs. permission denied vs. a malformed file) instead of matching
+// on error message text.
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// Sentinel errors. Wrap a lower-level error with one of these via Wrap,
+// then test the result with errors.Is (or the Is* helpers below).
+var (
+	ErrNotExist      = errors.New("does not exist")
+	ErrNotDir        = errors.New("not a directory")
+	ErrPermission    = errors.New("permission denied")
+	ErrInvalidFormat = errors.New("invalid format")
+	ErrIO            = errors.New("I/O error")
+)
+
+// Frame is one entry of a captured stack trace.
+type Frame struct {
+	Func string
+	File string
+	Line int
+}
+
+// Error wraps a sentinel (one of the Err* values above) together with the
+// underlying cause and the stack at the point Wrap was called, so a debug
+// build of MsgBox (or walk.ErrorDialog) can show the full trace instead of
+// just the top-level message.
+type Error struct {
+	kind  error
+	cause error
+	stack []Frame
+}
+
+// Wrap captures the current stack and returns an *Error reporting kind
+// (one of the sentinel Err* values) with cause as the underlying error.
+// cause may be nil if kind alone is the whole story.
+func Wrap(kind, cause error) *Error {
+	return &Error{kind: kind, cause: cause, stack: captureStack(2)}
+}
+
+func captureStack(skip int) []Frame {
+	var frames []Frame
+
+	for i := skip; ; i++ {
+		pc, file, line, ok := runtime.Caller(i)
+		if !ok {
+			break
+		}
+
+		name := "unknown"
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			name = fn.Name()
+		}
+
+		frames = append(frames, Frame{Func: name, File: file, Line: line})
+
+		if len(frames) >= 32 {
+			break
+		}
+	}
+
+	return frames
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s", e.kind, e.cause)
+	}
+	return e.kind.Error()
+}
+
+// Unwrap exposes the sentinel kind to errors.Is/errors.As, and the cause
+// via Cause, so callers can reach either the classification or the
+// original error that triggered it.
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+// Cause returns the error that was wrapped, which may itself be an *Error.
+func (e *Error) Cause() error {
+	return e.cause
+}
+
+// StackTrace returns the call stack captured when this error was wrapped,
+// innermost frame first.
+func (e *Error) StackTrace() []Frame {
+	return e.stack
+}
+
+func IsNotExist(err error) bool      { return errors.Is(err, ErrNotExist) }
+func IsNotDir(err error) bool        { return errors.Is(err, ErrNotDir) }
+func IsPermission(err error) bool    { return errors.Is(err, ErrPermission) }
+func IsInvalidFormat(err error) bool { return errors.Is(err, ErrInvalidFormat) }
+func IsIO(err error) bool            { return errors.Is(err, ErrIO) }
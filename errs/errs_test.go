@@ -0,0 +1,102 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWrapIsHelpers(t *testing.T) {
+	cause := errors.New("open /tmp/x: no such file or directory")
+
+	tests := []struct {
+		name string
+		kind error
+		is   func(error) bool
+	}{
+		{"NotExist", ErrNotExist, IsNotExist},
+		{"NotDir", ErrNotDir, IsNotDir},
+		{"Permission", ErrPermission, IsPermission},
+		{"InvalidFormat", ErrInvalidFormat, IsInvalidFormat},
+		{"IO", ErrIO, IsIO},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Wrap(tt.kind, cause)
+
+			if !tt.is(err) {
+				t.Errorf("Is%s(Wrap(%v, cause)) = false, want true", tt.name, tt.kind)
+			}
+
+			for _, other := range tests {
+				if other.kind == tt.kind {
+					continue
+				}
+				if other.is(err) {
+					t.Errorf("Is%s(Wrap(%v, cause)) = true, want false", other.name, tt.kind)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	cause := errors.New("permission denied")
+	err := Wrap(ErrPermission, cause)
+
+	if got, want := err.Error(), "permission denied: permission denied"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	bare := Wrap(ErrNotExist, nil)
+	if got, want := bare.Error(), ErrNotExist.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestCause(t *testing.T) {
+	cause := errors.New("boom")
+	err := Wrap(ErrIO, cause)
+
+	if err.Cause() != cause {
+		t.Errorf("Cause() = %v, want %v", err.Cause(), cause)
+	}
+}
+
+func TestErrorsAsUnwrapsToError(t *testing.T) {
+	cause := errors.New("boom")
+	wrapped := Wrap(ErrIO, cause)
+
+	var target *Error
+	if !errors.As(wrapped, &target) {
+		t.Fatal("errors.As(wrapped, &target) = false, want true")
+	}
+	if target != wrapped {
+		t.Errorf("errors.As found %v, want %v", target, wrapped)
+	}
+}
+
+func TestStackTraceNotEmpty(t *testing.T) {
+	err := Wrap(ErrIO, errors.New("boom"))
+
+	if len(err.StackTrace()) == 0 {
+		t.Error("StackTrace() is empty, want at least one frame")
+	}
+
+	if got := err.StackTrace()[0].Func; got == "" || got == "unknown" {
+		t.Errorf("StackTrace()[0].Func = %q, want a real function name", got)
+	}
+}
+
+func TestWrapNilCausePanicsNever(t *testing.T) {
+	// Wrap with a nil cause is a documented, supported case (kind alone
+	// is the whole story); it must not panic.
+	err := Wrap(ErrNotExist, nil)
+	if err.Cause() != nil {
+		t.Errorf("Cause() = %v, want nil", err.Cause())
+	}
+}
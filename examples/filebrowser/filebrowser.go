@@ -5,23 +5,40 @@
 package main
 
 import (
-	"os"
 	"path"
 	"time"
 )
 
 import "walk"
+import "walk/ignore"
 
 type FileInfo struct {
 	Name     string
 	Size     int64
 	Modified int64
+	IsDir    bool
 }
 
 type FileInfoModel struct {
-	items               []*FileInfo
-	rowsResetPublisher  walk.EventPublisher
-	rowChangedPublisher walk.IntEventPublisher
+	fs                   walk.FileSystem
+	ignoreMatcher        *ignore.Matcher
+	items                []*FileInfo
+	rowsResetPublisher   walk.EventPublisher
+	rowChangedPublisher  walk.IntEventPublisher
+	rowInsertedPublisher walk.IntEventPublisher
+	rowRemovedPublisher  walk.IntEventPublisher
+
+	rootPath string
+	dirPath  string
+	snapshot map[string]*dirSnapshot
+}
+
+func NewFileInfoModel(fs walk.FileSystem, ignoreMatcher *ignore.Matcher) *FileInfoModel {
+	return &FileInfoModel{
+		fs:            fs,
+		ignoreMatcher: ignoreMatcher,
+		snapshot:      make(map[string]*dirSnapshot),
+	}
 }
 
 func (m *FileInfoModel) Columns() []walk.TableColumn {
@@ -47,7 +64,7 @@ func (m *FileInfoModel) Value(row, col int) interface{} {
 		return item.Size
 
 	case 2:
-		return time.SecondsToLocalTime(item.Modified)
+		return time.Unix(item.Modified, 0)
 	}
 
 	panic("unexpected col")
@@ -61,96 +78,276 @@ func (m *FileInfoModel) RowChanged() *walk.IntEvent {
 	return m.rowChangedPublisher.Event()
 }
 
-func (m *FileInfoModel) ResetRows(dirPath string) os.Error {
-	dir, err := os.Open(dirPath)
+// RowInserted is published, with the index of the new row, when Refresh
+// finds a child that wasn't present in the last snapshot of the current
+// directory.
+func (m *FileInfoModel) RowInserted() *walk.IntEvent {
+	return m.rowInsertedPublisher.Event()
+}
+
+// RowRemoved is published, with the index of the removed row, when
+// Refresh finds that a previously listed child is gone.
+func (m *FileInfoModel) RowRemoved() *walk.IntEvent {
+	return m.rowRemovedPublisher.Event()
+}
+
+// ResetRows reads dirPath's children and replaces m.items with them. rootPath
+// is the TreeView root dirPath descends from (see rootForTreeViewItem):
+// ignoreMatcher's patterns are resolved relative to it, the same way
+// StreamingFileInfoModel.Start resolves them for DirWalker, so a given path
+// is excluded or not the same way regardless of which of the two ever reads
+// it.
+func (m *FileInfoModel) ResetRows(rootPath, dirPath string) error {
+	snap, err := snapshotDir(m.fs, m.ignoreMatcher, rootPath, dirPath, m.snapshot)
 	if err != nil {
 		return err
 	}
-	defer dir.Close()
 
-	names, err := dir.Readdirnames(-1)
+	m.rootPath = rootPath
+	m.dirPath = dirPath
+	m.items = snap.items
+	m.snapshot[dirPath] = snap
+
+	m.rowsResetPublisher.Publish()
+
+	return nil
+}
+
+// Refresh re-snapshots m.dirPath (see snapshotDir) and, rather than rebuilding m.items and
+// resetting the whole TableView (which flickers and loses the current
+// selection), diffs the new directory snapshot against the cached one and
+// publishes precise RowInserted/RowChanged/RowRemoved events so the view
+// can update in place.
+func (m *FileInfoModel) Refresh() error {
+	if m.dirPath == "" {
+		return nil
+	}
+
+	next, err := snapshotDir(m.fs, m.ignoreMatcher, m.rootPath, m.dirPath, m.snapshot)
 	if err != nil {
 		return err
 	}
 
-	m.items = make([]*FileInfo, 0, len(names))
-
-	for _, name := range names {
-		if !excludePath(name) {
-			fullPath := path.Join(dirPath, name)
+	prev := m.snapshot[m.dirPath]
+	if prev != nil && prev.hash == next.hash {
+		return nil
+	}
 
-			fi, err := os.Stat(fullPath)
-			if err != nil {
-				continue
-			}
+	inserted, changed, removed := diffSnapshots(prev, next)
 
-			item := &FileInfo{
-				Name:     name,
-				Size:     fi.Size,
-				Modified: fi.Mtime_ns / 1e9,
-			}
+	m.items = next.items
+	m.snapshot[m.dirPath] = next
 
-			m.items = append(m.items, item)
-		}
+	for _, i := range removed {
+		m.rowRemovedPublisher.Publish(i)
+	}
+	for _, i := range changed {
+		m.rowChangedPublisher.Publish(i)
+	}
+	for _, i := range inserted {
+		m.rowInsertedPublisher.Publish(i)
 	}
-
-	m.rowsResetPublisher.Publish()
 
 	return nil
 }
 
+// Watch polls Refresh every interval, on a background goroutine, via
+// walk.Synchronize so the resulting row events are published on the GUI
+// thread. It returns a stop func that ends the polling.
+func (m *FileInfoModel) Watch(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan bool)
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				walk.Synchronize(func() { m.Refresh() })
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
 type MainWindow struct {
 	*walk.MainWindow
-	fileInfoModel *FileInfoModel
-	treeView      *walk.TreeView
-	selTvwItem    *walk.TreeViewItem
-	tableView     *walk.TableView
-	preview       *walk.WebView
+	fs             walk.FileSystem
+	ignoreMatcher  *ignore.Matcher
+	fileInfoModel  *FileInfoModel
+	streamingModel *StreamingFileInfoModel
+	usingStreaming bool
+	stopWatch      func()
+	treeView       *walk.TreeView
+	selTvwItem     *walk.TreeViewItem
+	tableView      *walk.TableView
+	preview        *walk.WebView
 }
 
-func (mw *MainWindow) showError(err os.Error) {
+// scanSubtree switches the TableView over to a StreamingFileInfoModel and
+// starts it walking the selected node's subtree recursively in the
+// background, streaming rows in as DirWalker finds them instead of
+// blocking on a single fs.ReadDir the way FileInfoModel.ResetRows does.
+// Selecting a different TreeView node cancels it and switches the
+// TableView back to fileInfoModel; scanning again cancels it and starts a
+// new scan.
+func (mw *MainWindow) scanSubtree() {
+	if mw.selTvwItem == nil {
+		return
+	}
+
+	if mw.stopWatch != nil {
+		mw.stopWatch()
+		mw.stopWatch = nil
+	}
+
+	if mw.streamingModel == nil {
+		mw.streamingModel = NewStreamingFileInfoModel(mw.fs, mw.ignoreMatcher)
+	} else {
+		mw.streamingModel.Stop()
+	}
+
+	mw.tableView.SetModel(mw.streamingModel)
+	mw.usingStreaming = true
+	mw.streamingModel.Start(rootForTreeViewItem(mw.selTvwItem), pathForTreeViewItem(mw.selTvwItem))
+}
+
+// currentItemName returns the name of the TableView row at index, reading
+// from whichever model (fileInfoModel or, mid-scan, streamingModel) is
+// currently bound to it.
+func (mw *MainWindow) currentItemName(index int) string {
+	if mw.usingStreaming {
+		return mw.streamingModel.items[index].Name
+	}
+	return mw.fileInfoModel.items[index].Name
+}
+
+func (mw *MainWindow) showError(err error) {
 	if err == nil {
 		return
 	}
 
-	walk.MsgBox(mw, "Error", err.String(), walk.MsgBoxOK|walk.MsgBoxIconError)
+	walk.MsgBox(mw, "Error", err.Error(), walk.MsgBoxOK|walk.MsgBoxIconError)
 }
 
+// populateTreeViewItem lists parent's immediate subdirectories and adds
+// them as children, replacing the dummy child newTreeViewItem added to
+// make parent expandable. The read runs on a background goroutine so a
+// slow or network-backed directory doesn't block the GUI thread; the
+// TreeView is only touched back on the GUI thread via walk.Synchronize.
+//
+// It snapshots parent's directory through snapshotDir rather than a bare
+// fs.ReadDir, sharing fileInfoModel's path-keyed cache, so an expanded
+// TreeView node is folded into the same merkle tree as a directory
+// selected in the TableView: refreshTree can later tell whether parent's
+// contents changed without re-reading every node from scratch.
 func (mw *MainWindow) populateTreeViewItem(parent *walk.TreeViewItem) {
-	mw.treeView.SetSuspended(true)
-	defer mw.treeView.SetSuspended(false)
+	rootPath := rootForTreeViewItem(parent)
+	dirPath := pathForTreeViewItem(parent)
 
-	// Remove dummy child
-	parent.Children().Clear()
+	go func() {
+		snap, err := snapshotDir(mw.fs, mw.ignoreMatcher, rootPath, dirPath, mw.fileInfoModel.snapshot)
 
-	dirPath := pathForTreeViewItem(parent)
+		walk.Synchronize(func() {
+			mw.treeView.SetSuspended(true)
+			defer mw.treeView.SetSuspended(false)
+
+			// Remove dummy child
+			parent.Children().Clear()
+
+			if err != nil {
+				mw.showError(err)
+				return
+			}
+
+			mw.fileInfoModel.snapshot[dirPath] = snap
+
+			for _, fi := range snap.items {
+				if fi.IsDir {
+					parent.Children().Add(newTreeViewItem(fi.Name))
+				}
+			}
+		})
+	}()
+}
 
-	dir, err := os.Open(dirPath)
+// refreshTree re-snapshots item's directory, reusing fileInfoModel's
+// shared snapshot cache, and performs a merkle-trie walk down from there:
+// if item's hash is unchanged since it was last snapshotted, its entire
+// subtree is left untouched (not even read further) and the walk stops;
+// otherwise, if the set of subdirectories itself didn't change, the walk
+// recurses into item's existing TreeViewItem children (the change must
+// be deeper down, so item's own children don't need rebuilding), and
+// only rebuilds item's children outright when that set did change. Never
+// visited and currently collapsed nodes are skipped entirely.
+func (mw *MainWindow) refreshTree(item *walk.TreeViewItem) {
+	rootPath := rootForTreeViewItem(item)
+	dirPath := pathForTreeViewItem(item)
+	prev, wasCached := mw.fileInfoModel.snapshot[dirPath]
+
+	if !wasCached && !item.Expanded() {
+		return
+	}
+
+	next, err := snapshotDir(mw.fs, mw.ignoreMatcher, rootPath, dirPath, mw.fileInfoModel.snapshot)
 	if err != nil {
 		mw.showError(err)
 		return
 	}
-	defer dir.Close()
+	mw.fileInfoModel.snapshot[dirPath] = next
 
-	names, err := dir.Readdirnames(-1)
-	panicIfErr(err)
+	if wasCached && prev.hash == next.hash {
+		return
+	}
 
-	for _, name := range names {
-		fi, err := os.Stat(path.Join(dirPath, name))
-		panicIfErr(err)
+	if !item.Expanded() {
+		return
+	}
 
-		if !excludePath(name) && fi.IsDirectory() {
-			child := newTreeViewItem(name)
+	children := item.Children()
+	if children.Len() == 1 && children.At(0).Text() == "" {
+		return // not populated yet; the next expand will read fresh
+	}
 
-			parent.Children().Add(child)
+	if wasCached && sameChildDirNames(prev, next) {
+		for i := 0; i < children.Len(); i++ {
+			mw.refreshTree(children.At(i))
 		}
+		return
 	}
+
+	mw.populateTreeViewItem(item)
 }
 
-func panicIfErr(err os.Error) {
-	if err != nil {
-		panic(err)
-	}
+// watchTree polls refreshTree, every interval and on a background
+// goroutine via walk.Synchronize, over every root item of treeView (and,
+// transitively, whatever expanded descendants refreshTree's merkle walk
+// doesn't short-circuit). It returns a stop func that ends the polling.
+func (mw *MainWindow) watchTree(treeView *walk.TreeView, interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan bool)
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				walk.Synchronize(func() {
+					roots := treeView.Items()
+					for i := 0; i < roots.Len(); i++ {
+						mw.refreshTree(roots.At(i))
+					}
+				})
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
 }
 
 func pathForTreeViewItem(item *walk.TreeViewItem) string {
@@ -163,12 +360,37 @@ func pathForTreeViewItem(item *walk.TreeViewItem) string {
 	return path.Join(parts...)
 }
 
-func excludePath(path string) bool {
-	if path == "System Volume Information" {
+// rootForTreeViewItem returns the path of item's topmost ancestor, one of
+// the drive items added directly under treeView in main. A .walkignore's
+// patterns are resolved relative to this root rather than to item itself,
+// so a given child path is excluded the same way no matter which TreeView
+// node it was reached from, and the same way DirWalker resolves it for
+// StreamingFileInfoModel (see StreamingFileInfoModel.Start).
+func rootForTreeViewItem(item *walk.TreeViewItem) string {
+	for item.Parent() != nil {
+		item = item.Parent()
+	}
+
+	return item.Text()
+}
+
+// isExcluded reports whether relPath, expressed relative to the root
+// matcher's patterns are anchored to (see rootForTreeViewItem), should be
+// hidden from the tree and table views: either because it names the
+// well-known Windows system folder, or because matcher (loaded from a
+// .walkignore file, if any) excludes it.
+func isExcluded(matcher *ignore.Matcher, relPath string, isDir bool) bool {
+	if path.Base(relPath) == "System Volume Information" {
 		return true
 	}
 
-	return false
+	if matcher == nil {
+		return false
+	}
+
+	excluded, _ := matcher.Match(relPath, isDir)
+
+	return excluded
 }
 
 func newTreeViewItem(text string) *walk.TreeViewItem {
@@ -187,11 +409,28 @@ func main() {
 
 	mainWnd, _ := walk.NewMainWindow()
 
+	fs := walk.NewOSFileSystem()
+
+	ignoreMatcher, err := ignore.LoadIgnoreFile(".walkignore")
+	if err != nil {
+		ignoreMatcher = ignore.NewMatcher(nil)
+	}
+
+	settings := walk.NewIniFileSettings()
+	if err := settings.Load(); err != nil {
+		walk.MsgBox(nil, "Error", "Failed to load settings: "+err.Error(), walk.MsgBoxOK|walk.MsgBoxIconError)
+	}
+	binder := walk.NewSettingsBinder(settings)
+
 	mw := &MainWindow{
 		MainWindow:    mainWnd,
-		fileInfoModel: &FileInfoModel{},
+		fs:            fs,
+		ignoreMatcher: ignoreMatcher,
+		fileInfoModel: NewFileInfoModel(fs, ignoreMatcher),
 	}
 
+	binder.SaveFailed = mw.showError
+
 	mw.SetTitle("Walk File Browser Example")
 	mw.SetLayout(walk.NewHBoxLayout())
 
@@ -201,9 +440,23 @@ func main() {
 
 	exitAction := walk.NewAction()
 	exitAction.SetText("E&xit")
-	exitAction.Triggered().Attach(func() { walk.App().Exit(0) })
+	exitAction.Triggered().Attach(func() {
+		mw.showError(settings.Save())
+		walk.App().Exit(0)
+	})
 	fileMenu.Actions().Add(exitAction)
 
+	toolsMenu, _ := walk.NewMenu()
+	toolsMenuAction, _ := mw.Menu().Actions().AddMenu(toolsMenu)
+	toolsMenuAction.SetText("&Tools")
+
+	scanSubtreeAction := walk.NewAction()
+	scanSubtreeAction.SetText("&Scan Subtree Recursively")
+	scanSubtreeAction.Triggered().Attach(func() {
+		mw.scanSubtree()
+	})
+	toolsMenu.Actions().Add(scanSubtreeAction)
+
 	helpMenu, _ := walk.NewMenu()
 	helpMenuAction, _ := mw.Menu().Actions().AddMenu(helpMenu)
 	helpMenuAction.SetText("&Help")
@@ -227,8 +480,20 @@ func main() {
 	})
 
 	mw.treeView.SelectionChanged().Attach(func(old, new *walk.TreeViewItem) {
+		if mw.stopWatch != nil {
+			mw.stopWatch()
+			mw.stopWatch = nil
+		}
+
+		if mw.streamingModel != nil {
+			mw.streamingModel.Stop()
+		}
+		mw.usingStreaming = false
+
+		mw.tableView.SetModel(mw.fileInfoModel)
 		mw.selTvwItem = new
-		mw.showError(mw.fileInfoModel.ResetRows(pathForTreeViewItem(new)))
+		mw.showError(mw.fileInfoModel.ResetRows(rootForTreeViewItem(new), pathForTreeViewItem(new)))
+		mw.stopWatch = mw.fileInfoModel.Watch(2 * time.Second)
 	})
 
 	drives, _ := walk.DriveNames()
@@ -240,6 +505,8 @@ func main() {
 	}
 	mw.treeView.SetSuspended(false)
 
+	mw.watchTree(mw.treeView, 2*time.Second)
+
 	mw.tableView, _ = walk.NewTableView(splitter)
 	mw.tableView.SetModel(mw.fileInfoModel)
 	mw.tableView.SetSingleItemSelection(true)
@@ -249,8 +516,7 @@ func main() {
 
 		index := mw.tableView.CurrentIndex()
 		if index > -1 {
-			name := mw.fileInfoModel.items[index].Name
-			url = path.Join(pathForTreeViewItem(mw.selTvwItem), name)
+			url = path.Join(pathForTreeViewItem(mw.selTvwItem), mw.currentItemName(index))
 		}
 
 		mw.preview.SetURL(url)
@@ -260,6 +526,11 @@ func main() {
 
 	mw.SetMinMaxSize(walk.Size{600, 400}, walk.Size{})
 	mw.SetSize(walk.Size{800, 600})
+
+	binder.BindWindowBounds("filebrowser.window", mw)
+	binder.BindSplitterSizes("filebrowser.splitter", splitter)
+	binder.BindTreeViewExpansion("filebrowser.tree", mw.treeView)
+
 	mw.Show()
 
 	mw.Run()
@@ -0,0 +1,215 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path"
+	"sort"
+	"strings"
+
+	"walk"
+	"walk/ignore"
+)
+
+// dirSnapshot is the cached state of one directory's immediate children:
+// a hash per child (over name, size, modified time and directory flag,
+// or, for a subdirectory that has itself been visited, that
+// subdirectory's own dirSnapshot.hash) and a hash over the sorted list of
+// (name, childHash) pairs. Because a visited subdirectory's hash is
+// folded into its parent's the same way, a change anywhere below a
+// visited node propagates all the way up to the root of whatever's been
+// visited, so comparing just dirSnapshot.hash at any cached level tells
+// FileInfoModel.Refresh and MainWindow.refreshTree whether anything in
+// that entire subtree changed before either bothers doing more work.
+type dirSnapshot struct {
+	mtime       int64
+	hash        uint64
+	childHashes map[string]uint64
+	items       []*FileInfo
+}
+
+func hashChild(name string, size, modified int64, isDir bool) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%d\x00%d\x00%v", name, size, modified, isDir)
+	return h.Sum64()
+}
+
+// snapshotDir reads dirPath's immediate children and computes a
+// dirSnapshot for them, applying ignoreMatcher the same way ResetRows and
+// populateTreeViewItem do. root is dirPath's TreeView root (see
+// rootForTreeViewItem): each child is matched against ignoreMatcher by its
+// path relative to root, not dirPath, so a multi-segment or anchored
+// .walkignore pattern means the same thing no matter which directory
+// snapshotDir happens to be reading. cache holds every dirSnapshot computed
+// so far, keyed by absolute path: for a child that is itself a directory and
+// already has an entry in cache (i.e. the user has visited it, through
+// either the TreeView or the TableView), snapshotDir recomputes and
+// folds in that subdirectory's own hash instead of a shallow
+// (name,size,mtime) leaf hash, giving a real hash that propagates up
+// through however much of the tree has actually been visited. An
+// unvisited child is hashed shallowly, since reading a directory no one
+// has looked at yet would defeat the point of not rebuilding everything.
+//
+// Before doing any of that, snapshotDir stats dirPath itself and compares
+// against the mtime recorded the last time it was snapshotted: if they
+// match, dirPath's own set of entries can't have changed (only adding,
+// removing or renaming an entry touches a directory's mtime), so dirPath
+// is not read again: only its already-visited subdirectories are
+// re-snapshotted, and the cached snapshot is returned as-is unless one of
+// their hashes changed. It's an approximation, not a guarantee: a file
+// rewritten in place changes its own size/mtime without touching its
+// parent directory's mtime, so such a change is missed until something
+// else in the same directory also changes. FileInfoModel.ResetRows and
+// Refresh go through this same cache and so share that blind spot.
+func snapshotDir(fs walk.FileSystem, ignoreMatcher *ignore.Matcher, root, dirPath string, cache map[string]*dirSnapshot) (*dirSnapshot, error) {
+	info, err := fs.Stat(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if prev, visited := cache[dirPath]; visited && prev.mtime == info.Mtime() {
+		changed := false
+		for _, item := range prev.items {
+			childPath := path.Join(dirPath, item.Name)
+			if _, childVisited := cache[childPath]; !item.IsDir || !childVisited {
+				continue
+			}
+			childSnap, err := snapshotDir(fs, ignoreMatcher, root, childPath, cache)
+			if err != nil {
+				return nil, err
+			}
+			cache[childPath] = childSnap
+			if changed = childSnap.hash != prev.childHashes[item.Name]; changed {
+				break
+			}
+		}
+		if !changed {
+			return prev, nil
+		}
+	}
+
+	entries, err := fs.ReadDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	byName := make(map[string]walk.FileInfo, len(entries))
+	for _, fi := range entries {
+		if isExcluded(ignoreMatcher, relPath(root, path.Join(dirPath, fi.Name())), fi.IsDir()) {
+			continue
+		}
+		names = append(names, fi.Name())
+		byName[fi.Name()] = fi
+	}
+	sort.Strings(names)
+
+	snap := &dirSnapshot{childHashes: make(map[string]uint64, len(names))}
+	combined := fnv.New64a()
+
+	for _, name := range names {
+		fi := byName[name]
+		childPath := path.Join(dirPath, name)
+
+		var childHash uint64
+		if fi.IsDir() {
+			if _, visited := cache[childPath]; visited {
+				childSnap, err := snapshotDir(fs, ignoreMatcher, root, childPath, cache)
+				if err != nil {
+					return nil, err
+				}
+				cache[childPath] = childSnap
+				childHash = childSnap.hash
+			} else {
+				childHash = hashChild(fi.Name(), fi.Size(), fi.Mtime(), true)
+			}
+		} else {
+			childHash = hashChild(fi.Name(), fi.Size(), fi.Mtime(), false)
+		}
+
+		snap.childHashes[name] = childHash
+		snap.items = append(snap.items, &FileInfo{Name: fi.Name(), Size: fi.Size(), Modified: fi.Mtime(), IsDir: fi.IsDir()})
+
+		fmt.Fprintf(combined, "%s\x00%d", name, childHash)
+	}
+
+	snap.mtime = info.Mtime()
+	snap.hash = combined.Sum64()
+
+	return snap, nil
+}
+
+// relPath strips root from p, the same way walk.FilterFromMatcher strips a
+// DirWalker's root from the paths it matches, so ignore.Matcher sees
+// identical inputs for the same file whether it's reached via snapshotDir
+// or via DirWalker.
+func relPath(root, p string) string {
+	rel := strings.TrimPrefix(p, root)
+	return strings.TrimLeft(rel, "/")
+}
+
+// childDirNames returns the set of subdirectory names among snap's
+// children, used by refreshTree to tell whether a directory's set of
+// subdirectories changed (requiring its TreeViewItem's children to be
+// rebuilt) or whether the change was deeper in an already-visited
+// subtree (which refreshTree can instead recurse into directly).
+func childDirNames(snap *dirSnapshot) map[string]bool {
+	names := make(map[string]bool, len(snap.items))
+	for _, item := range snap.items {
+		if item.IsDir {
+			names[item.Name] = true
+		}
+	}
+	return names
+}
+
+// sameChildDirNames reports whether prev and next list the same set of
+// subdirectories, ignoring files and ignoring whether any of those
+// subdirectories' own contents changed.
+func sameChildDirNames(prev, next *dirSnapshot) bool {
+	prevNames, nextNames := childDirNames(prev), childDirNames(next)
+	if len(prevNames) != len(nextNames) {
+		return false
+	}
+	for name := range nextNames {
+		if !prevNames[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// diffSnapshots compares an old and new dirSnapshot and reports, in terms
+// of row indices into next.items, which rows were inserted, removed or
+// changed relative to prev.items. removed indices refer to prev.items.
+func diffSnapshots(prev, next *dirSnapshot) (inserted, changed, removed []int) {
+	if prev == nil {
+		for i := range next.items {
+			inserted = append(inserted, i)
+		}
+		return inserted, changed, removed
+	}
+
+	for i, item := range next.items {
+		oldHash, existed := prev.childHashes[item.Name]
+		if !existed {
+			inserted = append(inserted, i)
+			continue
+		}
+		if oldHash != next.childHashes[item.Name] {
+			changed = append(changed, i)
+		}
+	}
+
+	for i, item := range prev.items {
+		if _, stillThere := next.childHashes[item.Name]; !stillThere {
+			removed = append(removed, i)
+		}
+	}
+
+	return inserted, changed, removed
+}
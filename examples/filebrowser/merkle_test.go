@@ -0,0 +1,123 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"testing"
+
+	"walk"
+	"walk/ignore"
+)
+
+// trackingFS wraps a walk.FileSystem, counting ReadDir calls per path and
+// letting a test override the mtime Stat reports for a path, so
+// snapshotDir's "skip the ReadDir when mtime is unchanged" behavior can be
+// exercised without relying on a real clock.
+type trackingFS struct {
+	walk.FileSystem
+	mtimes       map[string]int64
+	readDirCalls map[string]int
+}
+
+func newTrackingFS(fs walk.FileSystem) *trackingFS {
+	return &trackingFS{FileSystem: fs, mtimes: make(map[string]int64), readDirCalls: make(map[string]int)}
+}
+
+func (fs *trackingFS) Stat(name string) (walk.FileInfo, error) {
+	info, err := fs.FileSystem.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if mt, ok := fs.mtimes[name]; ok {
+		return fakeFileInfo{FileInfo: info, mtime: mt}, nil
+	}
+	return info, nil
+}
+
+func (fs *trackingFS) ReadDir(name string) ([]walk.FileInfo, error) {
+	fs.readDirCalls[name]++
+	return fs.FileSystem.ReadDir(name)
+}
+
+type fakeFileInfo struct {
+	walk.FileInfo
+	mtime int64
+}
+
+func (fi fakeFileInfo) Mtime() int64 { return fi.mtime }
+
+func TestSnapshotDirSkipsUnchangedSubtree(t *testing.T) {
+	mem := walk.NewMemoryFileSystem()
+	mem.MkdirAll("root/sub", 0755)
+	f, _ := mem.OpenFile("root/sub/a.txt", os.O_CREATE|os.O_WRONLY, 0644)
+	f.Close()
+
+	fs := newTrackingFS(mem)
+	fs.mtimes["root/sub"] = 1
+
+	cache := make(map[string]*dirSnapshot)
+
+	subSnap, err := snapshotDir(fs, nil, "root", "root/sub", cache)
+	if err != nil {
+		t.Fatalf("snapshotDir(root/sub): %v", err)
+	}
+	cache["root/sub"] = subSnap
+
+	if fs.readDirCalls["root/sub"] != 1 {
+		t.Fatalf("readDirCalls[root/sub] = %d, want 1", fs.readDirCalls["root/sub"])
+	}
+
+	// root/sub's mtime hasn't changed: folding it into root's snapshot
+	// should reuse the cached snapshot rather than reading it again.
+	if _, err := snapshotDir(fs, nil, "root", "root", cache); err != nil {
+		t.Fatalf("snapshotDir(root): %v", err)
+	}
+	if fs.readDirCalls["root/sub"] != 1 {
+		t.Errorf("readDirCalls[root/sub] = %d after unchanged re-snapshot, want still 1", fs.readDirCalls["root/sub"])
+	}
+
+	// Now root/sub's mtime changes: it must be read again.
+	fs.mtimes["root/sub"] = 2
+	if _, err := snapshotDir(fs, nil, "root", "root", cache); err != nil {
+		t.Fatalf("snapshotDir(root) after mtime change: %v", err)
+	}
+	if fs.readDirCalls["root/sub"] != 2 {
+		t.Errorf("readDirCalls[root/sub] = %d after mtime change, want 2", fs.readDirCalls["root/sub"])
+	}
+}
+
+func TestSnapshotDirAppliesIgnoreMatcherRelativeToRoot(t *testing.T) {
+	mem := walk.NewMemoryFileSystem()
+	mem.MkdirAll("root/src/build", 0755)
+	mem.MkdirAll("root/build", 0755)
+
+	matcher := ignore.NewMatcher([]string{"/build"})
+
+	snap, err := snapshotDir(mem, matcher, "root", "root", make(map[string]*dirSnapshot))
+	if err != nil {
+		t.Fatalf("snapshotDir: %v", err)
+	}
+
+	for _, item := range snap.items {
+		if item.Name == "build" {
+			t.Errorf("anchored pattern /build should have excluded root's own build child")
+		}
+	}
+
+	srcSnap, err := snapshotDir(mem, matcher, "root", "root/src", make(map[string]*dirSnapshot))
+	if err != nil {
+		t.Fatalf("snapshotDir(root/src): %v", err)
+	}
+	found := false
+	for _, item := range srcSnap.items {
+		if item.Name == "build" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("anchored pattern /build should not exclude root/src/build (not at the ignore root)")
+	}
+}
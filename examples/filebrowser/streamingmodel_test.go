@@ -0,0 +1,84 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"walk"
+	"walk/ignore"
+)
+
+func TestStreamingFileInfoModelInsertsRowsAndFiltersByRoot(t *testing.T) {
+	fs := walk.NewMemoryFileSystem()
+	fs.MkdirAll("root/sub", 0755)
+	fs.MkdirAll("root/build", 0755)
+	for _, name := range []string{"root/a.txt", "root/sub/b.txt", "root/build/c.txt"} {
+		f, err := fs.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatalf("OpenFile(%s): %v", name, err)
+		}
+		f.Close()
+	}
+
+	matcher := ignore.NewMatcher([]string{"/build"})
+	m := NewStreamingFileInfoModel(fs, matcher)
+
+	var mu sync.Mutex
+	var insertedRows []int
+	m.RowInserted().Attach(func(row int) {
+		mu.Lock()
+		insertedRows = append(insertedRows, row)
+		mu.Unlock()
+	})
+	var resets int
+	m.RowsReset().Attach(func() {
+		mu.Lock()
+		resets++
+		mu.Unlock()
+	})
+
+	m.Start("root", "root")
+	defer m.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		walk.Synchronize(m.walker.SyncFunc)
+
+		mu.Lock()
+		n := m.RowCount()
+		mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for StreamingFileInfoModel to find entries")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if resets != 1 {
+		t.Errorf("resets = %d, want exactly 1 (from Start, not one per entry)", resets)
+	}
+	if len(insertedRows) != m.RowCount() {
+		t.Errorf("len(insertedRows) = %d, RowCount() = %d, want equal", len(insertedRows), m.RowCount())
+	}
+
+	var names []string
+	for i := 0; i < m.RowCount(); i++ {
+		names = append(names, m.Value(i, 0).(string))
+	}
+	for _, name := range names {
+		if name == "c.txt" {
+			t.Errorf("root/build is anchored to the ignore root and should have been filtered out, got names = %v", names)
+		}
+	}
+}
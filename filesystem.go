@@ -0,0 +1,215 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"walk/errs"
+)
+
+// FileInfo describes a file or directory entry returned by a FileSystem.
+// It mirrors the subset of os.FileInfo that walk's widgets rely on, with
+// Mtime in Unix seconds; OSFileSystem adapts os.FileInfo values to it.
+type FileInfo interface {
+	Name() string
+	Size() int64
+	Mtime() int64
+	IsDir() bool
+}
+
+// File is the interface a FileSystem hands back from Open/OpenFile. It is
+// intentionally small, matching the handful of operations walk actually
+// performs on files: streaming their contents and, for directories,
+// listing their children.
+type File interface {
+	Read(p []byte) (n int, err error)
+	Write(p []byte) (n int, err error)
+	Seek(offset int64, whence int) (ret int64, err error)
+	Close() error
+	Readdir(count int) ([]FileInfo, error)
+}
+
+// FileSystem abstracts the storage walk's widgets read from and write to,
+// so a TreeView or IniFileSettings can be pointed at a local disk, a zip
+// or tar archive, an in-memory tree, or any other implementation without
+// the caller changing. Errors returned by a FileSystem are wrapped with
+// walk/errs, so callers can use errs.IsNotExist and friends instead of
+// matching on message text.
+type FileSystem interface {
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm uint32) (File, error)
+	Stat(name string) (FileInfo, error)
+	ReadDir(name string) ([]FileInfo, error)
+	MkdirAll(path string, perm uint32) error
+	Remove(name string) error
+}
+
+// osFileInfo adapts an os.FileInfo to the FileInfo interface.
+type osFileInfo struct {
+	fi os.FileInfo
+}
+
+func (fi osFileInfo) Name() string { return fi.fi.Name() }
+func (fi osFileInfo) Size() int64  { return fi.fi.Size() }
+func (fi osFileInfo) Mtime() int64 { return fi.fi.ModTime().Unix() }
+func (fi osFileInfo) IsDir() bool  { return fi.fi.IsDir() }
+
+// osFile adapts *os.File to the File interface.
+type osFile struct {
+	f *os.File
+}
+
+func (f osFile) Read(p []byte) (int, error)  { return wrapN(f.f.Read(p)) }
+func (f osFile) Write(p []byte) (int, error) { return wrapN(f.f.Write(p)) }
+func (f osFile) Seek(offset int64, whence int) (int64, error) {
+	n, err := f.f.Seek(offset, whence)
+	return n, classifyOSError(err)
+}
+func (f osFile) Close() error { return classifyOSError(f.f.Close()) }
+
+func (f osFile) Readdir(count int) ([]FileInfo, error) {
+	fis, err := f.f.Readdir(count)
+	if err != nil {
+		return nil, classifyOSError(err)
+	}
+
+	result := make([]FileInfo, len(fis))
+	for i := range fis {
+		result[i] = osFileInfo{fis[i]}
+	}
+
+	return result, nil
+}
+
+func wrapN(n int, err error) (int, error) {
+	return n, classifyOSError(err)
+}
+
+// classifyOSError adapts an error from the os package into a walk/errs
+// error, preferring os.IsNotExist/os.IsPermission where they apply and
+// falling back to sniffing the message for the cases neither covers
+// (the same way the stdlib's own os.IsNotExist does internally).
+func classifyOSError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case os.IsNotExist(err):
+		return errs.Wrap(errs.ErrNotExist, err)
+	case os.IsPermission(err):
+		return errs.Wrap(errs.ErrPermission, err)
+	case contains(err.Error(), "not a directory"):
+		return errs.Wrap(errs.ErrNotDir, err)
+	default:
+		return errs.Wrap(errs.ErrIO, err)
+	}
+}
+
+func contains(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// OSFileSystem is the default FileSystem, delegating directly to the os
+// package. It is what every FileSystem-aware type in walk used before this
+// abstraction existed, and remains the default when none is configured.
+type OSFileSystem struct{}
+
+func NewOSFileSystem() *OSFileSystem {
+	return &OSFileSystem{}
+}
+
+func (*OSFileSystem) Open(name string) (File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, classifyOSError(err)
+	}
+
+	return osFile{f}, nil
+}
+
+func (*OSFileSystem) OpenFile(name string, flag int, perm uint32) (File, error) {
+	f, err := os.OpenFile(name, flag, os.FileMode(perm))
+	if err != nil {
+		return nil, classifyOSError(err)
+	}
+
+	return osFile{f}, nil
+}
+
+func (*OSFileSystem) Stat(name string) (FileInfo, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, classifyOSError(err)
+	}
+
+	return osFileInfo{fi}, nil
+}
+
+func (*OSFileSystem) ReadDir(name string) ([]FileInfo, error) {
+	dir, err := os.Open(name)
+	if err != nil {
+		return nil, classifyOSError(err)
+	}
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, classifyOSError(err)
+	}
+
+	infos := make([]FileInfo, 0, len(names))
+	for _, n := range names {
+		fi, err := os.Stat(path.Join(name, n))
+		if err != nil {
+			continue
+		}
+		infos = append(infos, osFileInfo{fi})
+	}
+
+	return infos, nil
+}
+
+func (*OSFileSystem) MkdirAll(path string, perm uint32) error {
+	return classifyOSError(os.MkdirAll(path, os.FileMode(perm)))
+}
+
+func (*OSFileSystem) Remove(name string) error {
+	return classifyOSError(os.Remove(name))
+}
+
+// NewFileSystemForPath inspects the extension of archivePath and returns a
+// read-only FileSystem rooted at its contents, dispatching to
+// ZipFileSystem, TarFileSystem, TarGzFileSystem or TarBz2FileSystem as
+// appropriate. Paths
+// with no recognized archive extension fall back to an OSFileSystem rooted
+// at the OS root, so callers can use it unconditionally.
+func NewFileSystemForPath(archivePath string) (FileSystem, error) {
+	lower := strings.ToLower(archivePath)
+
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return NewZipFileSystem(archivePath)
+
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return NewTarGzFileSystem(archivePath)
+
+	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz2"):
+		return NewTarBz2FileSystem(archivePath)
+
+	case strings.HasSuffix(lower, ".tar"):
+		return NewTarFileSystem(archivePath)
+	}
+
+	return NewOSFileSystem(), nil
+}
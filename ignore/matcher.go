@@ -0,0 +1,178 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ignore implements gitignore/dockerignore-style pattern matching,
+// so tools like walk's file browser can hide build artifacts and VCS
+// metadata without hardcoding a name list.
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// pattern is a single compiled line from an ignore file.
+type pattern struct {
+	regexp   *regexp.Regexp
+	negated  bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Matcher evaluates a path against an ordered list of gitignore-style
+// patterns. Later patterns take precedence over earlier ones, and a
+// leading "!" re-includes a path excluded by an earlier pattern.
+type Matcher struct {
+	patterns []pattern
+}
+
+// NewMatcher compiles patterns, one gitignore-style line each, in the
+// order given. Blank lines and lines starting with "#" are ignored, same
+// as in a .gitignore file.
+func NewMatcher(patterns []string) *Matcher {
+	m := &Matcher{}
+
+	for _, line := range patterns {
+		if p, ok := compilePattern(line); ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+
+	return m
+}
+
+// LoadIgnoreFile reads filePath line by line and compiles it into a
+// Matcher, the way git reads a .gitignore.
+func LoadIgnoreFile(filePath string) (*Matcher, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewMatcher(lines), nil
+}
+
+func compilePattern(line string) (pattern, bool) {
+	trimmed := strings.TrimRight(line, " \t")
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return pattern{}, false
+	}
+
+	p := pattern{}
+
+	if strings.HasPrefix(trimmed, "!") {
+		p.negated = true
+		trimmed = trimmed[1:]
+	}
+
+	if strings.HasPrefix(trimmed, "/") {
+		p.anchored = true
+		trimmed = trimmed[1:]
+	}
+
+	if strings.HasSuffix(trimmed, "/") {
+		p.dirOnly = true
+		trimmed = strings.TrimSuffix(trimmed, "/")
+	}
+
+	if trimmed == "" {
+		return pattern{}, false
+	}
+
+	if !p.anchored && !strings.Contains(trimmed, "/") {
+		// A pattern with no slash matches at any depth, same as git.
+		p.anchored = false
+	} else {
+		p.anchored = true
+	}
+
+	p.regexp = regexp.MustCompile(globToRegexp(trimmed, p.anchored))
+
+	return p, true
+}
+
+// globToRegexp translates a gitignore glob (supporting *, ?, [...] and **)
+// into an anchored regular expression matching a forward-slash path.
+func globToRegexp(glob string, anchored bool) string {
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				// "**" matches any number of path components.
+				j := i + 2
+				if j < len(runes) && runes[j] == '/' {
+					j++
+				}
+				b.WriteString(".*")
+				i = j - 1
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '.', '+', '(', ')', '|', '^', '$':
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		case '[':
+			j := i
+			for j < len(runes) && runes[j] != ']' {
+				j++
+			}
+			if j < len(runes) {
+				b.WriteString(string(runes[i : j+1]))
+				i = j
+			} else {
+				b.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		default:
+			b.WriteRune(c)
+		}
+	}
+
+	b.WriteString("(?:/.*)?$")
+
+	return b.String()
+}
+
+// Match reports whether relPath (using forward slashes, relative to the
+// root the Matcher was built for) is excluded by the pattern list, and
+// whether that verdict came from a negated ("!") pattern. Evaluation is
+// last-match-wins across the ordered pattern list, matching git's own
+// semantics.
+func (m *Matcher) Match(relPath string, isDir bool) (excluded, negated bool) {
+	relPath = path.Clean(relPath)
+
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+
+		if p.regexp.MatchString(relPath) {
+			excluded = !p.negated
+			negated = p.negated
+		}
+	}
+
+	return excluded, negated
+}
@@ -0,0 +1,94 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ignore
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMatchBasic(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		excluded bool
+	}{
+		{"plain name matches at any depth", []string{"build"}, "build", true, true},
+		{"plain name matches nested", []string{"build"}, "src/build", true, true},
+		{"unrelated name not excluded", []string{"build"}, "src", true, false},
+		{"anchored only matches at root", []string{"/build"}, "build", true, true},
+		{"anchored does not match nested", []string{"/build"}, "src/build", true, false},
+		{"dirOnly skips files", []string{"logs/"}, "logs", false, false},
+		{"dirOnly matches dirs", []string{"logs/"}, "logs", true, true},
+		{"star matches within a component", []string{"*.tmp"}, "a.tmp", false, true},
+		{"unanchored star pattern matches in a subdirectory", []string{"*.tmp"}, "a/b.tmp", false, true},
+		{"question mark matches one rune", []string{"a?c"}, "abc", false, true},
+		{"question mark rejects extra runes", []string{"a?c"}, "abcc", false, false},
+		{"bracket class matches", []string{"[ab].txt"}, "a.txt", false, true},
+		{"bracket class rejects others", []string{"[ab].txt"}, "c.txt", false, false},
+		{"double star matches across components", []string{"**/vendor"}, "a/b/vendor", true, true},
+		{"double star matches zero components", []string{"**/vendor"}, "vendor", true, true},
+		{"comment line is ignored", []string{"# build"}, "build", true, false},
+		{"blank line is ignored", []string{""}, "build", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMatcher(tt.patterns)
+
+			excluded, _ := m.Match(tt.path, tt.isDir)
+			if excluded != tt.excluded {
+				t.Errorf("Match(%q, %v) excluded = %v, want %v", tt.path, tt.isDir, excluded, tt.excluded)
+			}
+		})
+	}
+}
+
+func TestMatchNegation(t *testing.T) {
+	m := NewMatcher([]string{"*.log", "!keep.log"})
+
+	if excluded, negated := m.Match("debug.log", false); !excluded || negated {
+		t.Errorf("Match(debug.log) = (%v, %v), want (true, false)", excluded, negated)
+	}
+
+	if excluded, negated := m.Match("keep.log", false); excluded || !negated {
+		t.Errorf("Match(keep.log) = (%v, %v), want (false, true)", excluded, negated)
+	}
+}
+
+func TestMatchLastPatternWins(t *testing.T) {
+	m := NewMatcher([]string{"!build", "build"})
+
+	if excluded, _ := m.Match("build", true); !excluded {
+		t.Error("Match(build) = false, want true (later pattern should win)")
+	}
+}
+
+func TestLoadIgnoreFileMissing(t *testing.T) {
+	if _, err := LoadIgnoreFile("does-not-exist.walkignore"); err == nil {
+		t.Error("LoadIgnoreFile(missing) = nil error, want non-nil")
+	}
+}
+
+func TestLoadIgnoreFile(t *testing.T) {
+	path := t.TempDir() + "/.walkignore"
+	if err := os.WriteFile(path, []byte("# comment\n\nbuild/\n*.tmp\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	m, err := LoadIgnoreFile(path)
+	if err != nil {
+		t.Fatalf("LoadIgnoreFile: %v", err)
+	}
+
+	if excluded, _ := m.Match("build", true); !excluded {
+		t.Error("Match(build, true) = false, want true")
+	}
+	if excluded, _ := m.Match("a.tmp", false); !excluded {
+		t.Error("Match(a.tmp, false) = false, want true")
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"strings"
+
+	"walk/ignore"
+)
+
+// FilterFromMatcher adapts an ignore.Matcher into a DirWalker Filter, so a
+// .walkignore file can drive both the synchronous tree population and the
+// asynchronous DirWalker with the same pattern list. root is the directory
+// matcher's patterns are relative to, the same way a .gitignore's are
+// relative to the directory it lives in: the path DirWalker.Start is called
+// with or one of its ancestors. root is stripped from each path Filter sees
+// before matching.
+func FilterFromMatcher(root string, matcher *ignore.Matcher) Filter {
+	root = strings.TrimRight(root, `/\`)
+
+	return func(path string, fi FileInfo) bool {
+		rel := strings.TrimPrefix(path, root)
+		rel = strings.TrimLeft(rel, `/\`)
+
+		excluded, _ := matcher.Match(rel, fi.IsDir())
+		return !excluded
+	}
+}
@@ -6,39 +6,82 @@ package walk
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strings"
+
+	"walk/errs"
+)
+
+// iniLineKind classifies one physical line of an ini file, so Save can
+// rewrite only the lines that changed and emit everything else byte for
+// byte.
+type iniLineKind int
+
+const (
+	// iniLineOther is a blank line, a comment ("; ..." or "# ..."), or
+	// anything else IniFileSettings doesn't interpret. Its raw text is
+	// written back unchanged.
+	iniLineOther iniLineKind = iota
+	iniLineSection
+	iniLineKeyValue
 )
 
+// iniLine is one line of a loaded ini file, recorded so Save can
+// reconstruct the file in its original order and preserve whatever it
+// didn't understand instead of clobbering a hand-edited config.
+type iniLine struct {
+	kind    iniLineKind
+	raw     string // verbatim text, used as-is for iniLineOther and iniLineSection
+	section string // section this line's key belongs to (iniLineKeyValue only)
+	key     string // unqualified key, i.e. without the "section." prefix
+}
+
+// IniFileSettings is a Settings backend storing key=value pairs in an
+// ini-style file, with "[section]" headers mapped to "section.key" in
+// the flat key space Settings exposes. Comments and blank lines are
+// preserved across Load/Save, and any line Save can't attribute to a
+// known key is written back verbatim, so hand-editing settings.ini
+// doesn't get clobbered by the application saving its own changes.
 type IniFileSettings struct {
-	data map[string]string
+	stringMapSettings
+	fs    FileSystem
+	lines []iniLine
 }
 
 func NewIniFileSettings() *IniFileSettings {
-	return &IniFileSettings{data: make(map[string]string)}
+	return &IniFileSettings{
+		stringMapSettings: newStringMapSettings(),
+		fs:                NewOSFileSystem(),
+	}
 }
 
-func (ifs *IniFileSettings) Get(key string) (string, bool) {
-	val, ok := ifs.data[key]
-	return val, ok
+// SetFileSystem overrides the FileSystem settings.ini is read from and
+// written to. It defaults to an OSFileSystem rooted at the OS root, so
+// callers wanting a virtual or read-only root (e.g. a CopyOnWriteFileSystem
+// over a ZipFileSystem) can swap it in before calling Load or Save.
+func (ifs *IniFileSettings) SetFileSystem(fs FileSystem) {
+	ifs.fs = fs
 }
 
-func (ifs *IniFileSettings) Put(key, value string) os.Error {
-	if strings.IndexAny(key, "=\r\n") > -1 || strings.IndexAny(value, "\r\n") > -1 {
-		return newError("either key or value contains at least one of the invalid characters '=\\r\\n'")
+// Put shadows stringMapSettings.Put to reject keys or values that can't
+// round-trip through the "key=value" / "[section]" ini syntax, then
+// delegates to the shared implementation.
+func (ifs *IniFileSettings) Put(key, value string) error {
+	if strings.IndexAny(key, "=\r\n[]") > -1 || strings.IndexAny(value, "\r\n") > -1 {
+		return errs.Wrap(errs.ErrInvalidFormat, errors.New("key or value contains a character that can't round-trip through an ini file"))
 	}
 
-	ifs.data[key] = value
-
-	return nil
+	return ifs.stringMapSettings.Put(key, value)
 }
 
-func (ifs *IniFileSettings) filePath() (string, os.Error) {
+func (ifs *IniFileSettings) filePath() (string, error) {
 	appDataPath, err := AppDataPath()
 	if err != nil {
-		return "", err
+		return "", classifyOSError(err)
 	}
 
 	return path.Join(
@@ -48,39 +91,60 @@ func (ifs *IniFileSettings) filePath() (string, os.Error) {
 		"settings.ini"), nil
 }
 
-func (ifs *IniFileSettings) fileExists() (bool, os.Error) {
+// fileExists reports whether settings.ini is present, consulting the
+// typed error from Stat rather than treating every failure (including
+// permission errors and I/O errors) as "file does not exist".
+func (ifs *IniFileSettings) fileExists() (bool, error) {
 	filePath, err := ifs.filePath()
 	if err != nil {
 		return false, err
 	}
 
-	_, err = os.Stat(filePath)
-	if err != nil {
-		// FIXME: Not necessarily a file does not exist error.
-		return false, nil
-	}
-
-	return true, nil
+	return settingsFileExists(ifs.fs, filePath)
 }
 
-func (ifs *IniFileSettings) withFile(flags int, f func(file *os.File) os.Error) os.Error {
+func (ifs *IniFileSettings) withFile(flags int, f func(file File) error) error {
 	filePath, err := ifs.filePath()
+	if err != nil {
+		return err
+	}
 
 	dirPath, _ := path.Split(filePath)
-	if err := os.MkdirAll(dirPath, 0644); err != nil {
-		return wrapError(err)
+	if err := ifs.fs.MkdirAll(dirPath, 0644); err != nil {
+		return err
 	}
 
-	file, err := os.OpenFile(filePath, flags, 0644)
+	file, err := ifs.fs.OpenFile(filePath, flags, 0644)
 	if err != nil {
-		return wrapError(err)
+		return err
 	}
 	defer file.Close()
 
 	return f(file)
 }
 
-func (ifs *IniFileSettings) Load() os.Error {
+// iniFlatKey joins section and key into the dotted form Settings
+// exposes. An empty section (a key above any "[section]" header) maps
+// straight to key, unqualified.
+func iniFlatKey(section, key string) string {
+	if section == "" {
+		return key
+	}
+	return section + "." + key
+}
+
+// iniSplitFlatKey is iniFlatKey's inverse: it splits a dotted key on its
+// first "." into the section that would have produced it and the
+// unqualified key, assuming (as iniFlatKey does) that section names
+// don't themselves contain dots.
+func iniSplitFlatKey(flat string) (section, key string) {
+	if idx := strings.Index(flat, "."); idx != -1 {
+		return flat[:idx], flat[idx+1:]
+	}
+	return "", flat
+}
+
+func (ifs *IniFileSettings) Load() error {
 	exists, err := ifs.fileExists()
 	if err != nil {
 		return err
@@ -90,7 +154,10 @@ func (ifs *IniFileSettings) Load() os.Error {
 		return nil
 	}
 
-	return ifs.withFile(os.O_RDONLY, func(file *os.File) os.Error {
+	return ifs.withFile(os.O_RDONLY, func(file File) error {
+		ifs.lines = ifs.lines[:0]
+		section := ""
+
 		lineBytes := make([]byte, 0, 4096)
 		reader := bufio.NewReader(file)
 
@@ -100,10 +167,10 @@ func (ifs *IniFileSettings) Load() os.Error {
 			for {
 				ln, isPrefix, err := reader.ReadLine()
 				if err != nil {
-					if err == os.EOF {
+					if err == io.EOF {
 						return nil
 					}
-					return wrapError(err)
+					return errs.Wrap(errs.ErrIO, err)
 				}
 
 				lineBytes = append(lineBytes, ln...)
@@ -114,33 +181,105 @@ func (ifs *IniFileSettings) Load() os.Error {
 			}
 
 			lineStr := string(lineBytes)
-			assignIndex := strings.Index(lineStr, "=")
-			if assignIndex == -1 {
-				return newError("bad line format: missing '='")
-			}
+			trimmed := strings.TrimSpace(lineStr)
 
-			key := strings.TrimSpace(lineStr[:assignIndex])
-			val := strings.TrimSpace(lineStr[assignIndex+1:])
+			switch {
+			case trimmed == "" || strings.HasPrefix(trimmed, ";") || strings.HasPrefix(trimmed, "#"):
+				ifs.lines = append(ifs.lines, iniLine{kind: iniLineOther, raw: lineStr})
 
-			ifs.data[key] = val
-		}
+			case strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]"):
+				section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+				ifs.lines = append(ifs.lines, iniLine{kind: iniLineSection, raw: lineStr})
 
-		return nil
+			default:
+				assignIndex := strings.Index(lineStr, "=")
+				if assignIndex == -1 {
+					ifs.lines = append(ifs.lines, iniLine{kind: iniLineOther, raw: lineStr})
+					continue
+				}
+
+				key := strings.TrimSpace(lineStr[:assignIndex])
+				val := strings.TrimSpace(lineStr[assignIndex+1:])
+
+				ifs.lines = append(ifs.lines, iniLine{kind: iniLineKeyValue, section: section, key: key})
+				ifs.set(iniFlatKey(section, key), val)
+			}
+		}
 	})
 }
 
-func (ifs *IniFileSettings) Save() os.Error {
-	return ifs.withFile(os.O_CREATE|os.O_TRUNC|os.O_WRONLY, func(file *os.File) os.Error {
+func (ifs *IniFileSettings) Save() error {
+	return ifs.withFile(os.O_CREATE|os.O_TRUNC|os.O_WRONLY, func(file File) error {
 		bufWriter := bufio.NewWriter(file)
 
-		for key, val := range ifs.data {
-			line := fmt.Sprintf("%s=%s\n", key, val)
+		writeLine := func(s string) error {
+			if _, err := bufWriter.WriteString(s); err != nil {
+				return errs.Wrap(errs.ErrIO, err)
+			}
+			return bufWriter.WriteByte('\n')
+		}
+
+		written := make(map[string]bool, len(ifs.lines))
+
+		for _, line := range ifs.lines {
+			if line.kind != iniLineKeyValue {
+				if err := writeLine(line.raw); err != nil {
+					return err
+				}
+				continue
+			}
+
+			flat := iniFlatKey(line.section, line.key)
+			val, ok := ifs.Get(flat)
+			if !ok {
+				// Key was present on Load but has since been removed;
+				// drop its line rather than write a stale value.
+				continue
+			}
 
-			if _, err := bufWriter.WriteString(line); err != nil {
-				return wrapError(err)
+			if err := writeLine(fmt.Sprintf("%s=%s", line.key, val)); err != nil {
+				return err
 			}
+			written[flat] = true
 		}
 
-		return bufWriter.Flush()
+		// Keys set after Load (or before any Load at all) don't have a
+		// line yet: append them, grouped by section in first-seen
+		// order, each group under a freshly written "[section]" header.
+		var sectionOrder []string
+		bySection := make(map[string][]string)
+
+		for _, flat := range ifs.Keys() {
+			if written[flat] {
+				continue
+			}
+
+			section, key := iniSplitFlatKey(flat)
+			if _, ok := bySection[section]; !ok {
+				sectionOrder = append(sectionOrder, section)
+			}
+			bySection[section] = append(bySection[section], key)
+		}
+
+		for _, section := range sectionOrder {
+			if section != "" {
+				if err := writeLine(fmt.Sprintf("[%s]", section)); err != nil {
+					return err
+				}
+			}
+
+			for _, key := range bySection[section] {
+				val, _ := ifs.Get(iniFlatKey(section, key))
+				if err := writeLine(fmt.Sprintf("%s=%s", key, val)); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := bufWriter.Flush(); err != nil {
+			return errs.Wrap(errs.ErrIO, err)
+		}
+
+		return nil
 	})
 }
@@ -0,0 +1,103 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"walk/errs"
+)
+
+func TestIniFileSettingsSaveLoadRoundTrip(t *testing.T) {
+	fs := NewMemoryFileSystem()
+
+	ifs := NewIniFileSettings()
+	ifs.SetFileSystem(fs)
+
+	if err := ifs.Load(); err != nil {
+		t.Fatalf("Load (no file yet): %v", err)
+	}
+
+	if err := ifs.Put("window.width", "800"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := ifs.Put("window.height", "600"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if err := ifs.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reloaded := NewIniFileSettings()
+	reloaded.SetFileSystem(fs)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load (after Save): %v", err)
+	}
+
+	if v, ok := reloaded.Get("window.width"); !ok || v != "800" {
+		t.Errorf("Get(window.width) = (%q, %v), want (\"800\", true)", v, ok)
+	}
+	if v, ok := reloaded.Get("window.height"); !ok || v != "600" {
+		t.Errorf("Get(window.height) = (%q, %v), want (\"600\", true)", v, ok)
+	}
+}
+
+func TestIniFileSettingsPutRejectsNewlines(t *testing.T) {
+	ifs := NewIniFileSettings()
+	ifs.SetFileSystem(NewMemoryFileSystem())
+
+	if err := ifs.Put("key", "a\nb"); !errs.IsInvalidFormat(err) {
+		t.Errorf("Put(value with newline) = %v, want errs.ErrInvalidFormat", err)
+	}
+
+	if err := ifs.Put("a=b", "value"); !errs.IsInvalidFormat(err) {
+		t.Errorf("Put(key with '=') = %v, want errs.ErrInvalidFormat", err)
+	}
+}
+
+func TestIniFileSettingsPreservesUnknownLines(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	path := "Walk/FileBrowser/settings.ini"
+	fs.MkdirAll("Walk/FileBrowser", 0755)
+	f, err := fs.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	f.Write([]byte("; a hand-written comment\nexisting=1\n"))
+	f.Close()
+
+	ifs := NewIniFileSettings()
+	ifs.SetFileSystem(fs)
+	if err := ifs.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if err := ifs.Put("existing", "2"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := ifs.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	f, err = fs.Open(path)
+	if err != nil {
+		t.Fatalf("Open after Save: %v", err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, 4096)
+	n, _ := f.Read(buf)
+	content := string(buf[:n])
+
+	if !strings.Contains(content, "; a hand-written comment") {
+		t.Errorf("Save dropped the hand-written comment, got:\n%s", content)
+	}
+	if !strings.Contains(content, "existing=2") {
+		t.Errorf("Save didn't write the updated value, got:\n%s", content)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"walk/errs"
+)
+
+// JSONFileSettings is a Settings backend storing key/value pairs as a
+// JSON object, with dotted keys ("window.width") mapped to and from
+// nested objects so the file on disk reads like a normal hand-written
+// config rather than a flat bag of dotted strings. Arrays aren't
+// supported; a value that isn't a JSON object, number, bool or string is
+// kept as opaque JSON text and written back unchanged.
+type JSONFileSettings struct {
+	stringMapSettings
+	fs   FileSystem
+	path string
+
+	// kinds remembers, per flat key, which JSON literal the value was
+	// parsed from ('n'umber, 'b'ool, 'j'son, or the default string) so
+	// Save can write it back as that kind instead of always quoting it.
+	kinds map[string]byte
+}
+
+func NewJSONFileSettings(path string) *JSONFileSettings {
+	return &JSONFileSettings{
+		stringMapSettings: newStringMapSettings(),
+		fs:                NewOSFileSystem(),
+		path:              path,
+		kinds:             make(map[string]byte),
+	}
+}
+
+// SetFileSystem overrides the FileSystem path is read from and written
+// to. It defaults to an OSFileSystem rooted at the OS root.
+func (s *JSONFileSettings) SetFileSystem(fs FileSystem) {
+	s.fs = fs
+}
+
+func (s *JSONFileSettings) Load() error {
+	exists, err := settingsFileExists(s.fs, s.path)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	data, err := readSettingsFile(s.fs, s.path)
+	if err != nil {
+		return err
+	}
+
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return errs.Wrap(errs.ErrInvalidFormat, err)
+	}
+
+	s.flatten("", tree)
+
+	return nil
+}
+
+func (s *JSONFileSettings) flatten(prefix string, tree map[string]interface{}) {
+	for key, value := range tree {
+		flat := key
+		if prefix != "" {
+			flat = prefix + "." + key
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			s.flatten(flat, v)
+
+		case bool:
+			s.kinds[flat] = 'b'
+			s.set(flat, strconv.FormatBool(v))
+
+		case float64:
+			s.kinds[flat] = 'n'
+			s.set(flat, formatJSONNumber(v))
+
+		case string:
+			s.set(flat, v)
+
+		default:
+			// null, array, or anything else: keep it as opaque JSON
+			// text rather than lossily stringifying it.
+			s.kinds[flat] = 'j'
+			raw, _ := json.Marshal(v)
+			s.set(flat, string(raw))
+		}
+	}
+}
+
+// PutBool stores value and records it as a JSON bool kind, so Save writes
+// it back as a bare true/false instead of falling through to
+// stringMapSettings.PutBool's quoted "true"/"false" - kinds otherwise only
+// gets populated for a key that's been through Load, which a fresh, never
+// loaded JSONFileSettings never has.
+func (s *JSONFileSettings) PutBool(key string, value bool) error {
+	s.kinds[key] = 'b'
+	return s.stringMapSettings.PutBool(key, value)
+}
+
+// PutInt stores value and records it as a JSON number kind, for the same
+// reason PutBool does.
+func (s *JSONFileSettings) PutInt(key string, value int) error {
+	s.kinds[key] = 'n'
+	return s.stringMapSettings.PutInt(key, value)
+}
+
+func formatJSONNumber(v float64) string {
+	if v == float64(int64(v)) {
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func (s *JSONFileSettings) Save() error {
+	tree := make(map[string]interface{})
+
+	for _, key := range s.Keys() {
+		val, _ := s.Get(key)
+		setNestedJSON(tree, strings.Split(key, "."), s.jsonValue(key, val))
+	}
+
+	data, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return errs.Wrap(errs.ErrIO, err)
+	}
+
+	return writeSettingsFile(s.fs, s.path, data)
+}
+
+func (s *JSONFileSettings) jsonValue(key, val string) interface{} {
+	switch s.kinds[key] {
+	case 'b', 'n', 'j':
+		return json.RawMessage(val)
+	default:
+		return val
+	}
+}
+
+func setNestedJSON(tree map[string]interface{}, parts []string, value interface{}) {
+	if len(parts) == 1 {
+		tree[parts[0]] = value
+		return
+	}
+
+	child, ok := tree[parts[0]].(map[string]interface{})
+	if !ok {
+		child = make(map[string]interface{})
+		tree[parts[0]] = child
+	}
+
+	setNestedJSON(child, parts[1:], value)
+}
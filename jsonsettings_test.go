@@ -0,0 +1,77 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONFileSettingsPutBoolPutIntWriteBareLiterals(t *testing.T) {
+	fs := NewMemoryFileSystem()
+
+	s := NewJSONFileSettings("settings.json")
+	s.SetFileSystem(fs)
+
+	// No Load call: kinds must still get populated directly by PutBool and
+	// PutInt, not only by parsing an existing file.
+	if err := s.PutBool("window.maximized", true); err != nil {
+		t.Fatalf("PutBool: %v", err)
+	}
+	if err := s.PutInt("window.width", 800); err != nil {
+		t.Fatalf("PutInt: %v", err)
+	}
+
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	f, err := fs.Open("settings.json")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, 4096)
+	n, _ := f.Read(buf)
+	content := string(buf[:n])
+
+	if strings.Contains(content, `"true"`) || !strings.Contains(content, "true") {
+		t.Errorf("PutBool(true) was written as a quoted string, got:\n%s", content)
+	}
+	if strings.Contains(content, `"800"`) || !strings.Contains(content, "800") {
+		t.Errorf("PutInt(800) was written as a quoted string, got:\n%s", content)
+	}
+}
+
+func TestJSONFileSettingsRoundTrip(t *testing.T) {
+	fs := NewMemoryFileSystem()
+
+	s := NewJSONFileSettings("settings.json")
+	s.SetFileSystem(fs)
+	s.PutBool("a.flag", true)
+	s.PutInt("a.count", 42)
+	s.Put("a.name", "hi")
+
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reloaded := NewJSONFileSettings("settings.json")
+	reloaded.SetFileSystem(fs)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if v, ok := reloaded.GetBool("a.flag"); !ok || !v {
+		t.Errorf("GetBool(a.flag) = (%v, %v), want (true, true)", v, ok)
+	}
+	if v, ok := reloaded.GetInt("a.count"); !ok || v != 42 {
+		t.Errorf("GetInt(a.count) = (%v, %v), want (42, true)", v, ok)
+	}
+	if v, ok := reloaded.Get("a.name"); !ok || v != "hi" {
+		t.Errorf("Get(a.name) = (%q, %v), want (\"hi\", true)", v, ok)
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"walk/errs"
+)
+
+// memNode is either a directory (children != nil) or a file (children ==
+// nil; data is nil for an empty file).
+type memNode struct {
+	name     string
+	children map[string]*memNode
+	data     []byte
+	mtime    int64
+}
+
+func (n *memNode) isDir() bool { return n.children != nil }
+
+func (n *memNode) info() FileInfo {
+	size := int64(0)
+	if !n.isDir() {
+		size = int64(len(n.data))
+	}
+	return memFileInfo{name: n.name, size: size, mtime: n.mtime, isDir: n.isDir()}
+}
+
+type memFileInfo struct {
+	name  string
+	size  int64
+	mtime int64
+	isDir bool
+}
+
+func (fi memFileInfo) Name() string { return fi.name }
+func (fi memFileInfo) Size() int64  { return fi.size }
+func (fi memFileInfo) Mtime() int64 { return fi.mtime }
+func (fi memFileInfo) IsDir() bool  { return fi.isDir }
+
+// MemoryFileSystem is a FileSystem backed entirely by an in-memory tree.
+// It is primarily useful for tests and for synthetic roots that have no
+// backing storage of their own.
+type MemoryFileSystem struct {
+	mutex sync.Mutex
+	root  *memNode
+}
+
+func NewMemoryFileSystem() *MemoryFileSystem {
+	return &MemoryFileSystem{root: &memNode{name: "/", children: make(map[string]*memNode)}}
+}
+
+func (fs *MemoryFileSystem) split(name string) []string {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" || name == "." {
+		return nil
+	}
+	return strings.Split(name, "/")
+}
+
+func notExist(name string) error {
+	return errs.Wrap(errs.ErrNotExist, errors.New("no such file or directory: "+name))
+}
+
+func notDir(name string) error {
+	return errs.Wrap(errs.ErrNotDir, errors.New("not a directory: "+name))
+}
+
+func (fs *MemoryFileSystem) lookup(name string) (*memNode, error) {
+	node := fs.root
+	for _, part := range fs.split(name) {
+		if !node.isDir() {
+			return nil, notDir(name)
+		}
+		child, ok := node.children[part]
+		if !ok {
+			return nil, notExist(name)
+		}
+		node = child
+	}
+	return node, nil
+}
+
+func (fs *MemoryFileSystem) MkdirAll(p string, perm uint32) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	node := fs.root
+	for _, part := range fs.split(p) {
+		if !node.isDir() {
+			return notDir(p)
+		}
+		child, ok := node.children[part]
+		if !ok {
+			child = &memNode{name: part, children: make(map[string]*memNode)}
+			node.children[part] = child
+		}
+		node = child
+	}
+
+	return nil
+}
+
+func (fs *MemoryFileSystem) Remove(name string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	parts := fs.split(name)
+	if len(parts) == 0 {
+		return errs.Wrap(errs.ErrPermission, errors.New("cannot remove root"))
+	}
+
+	parent := fs.root
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := parent.children[part]
+		if !ok || !child.isDir() {
+			return notExist(name)
+		}
+		parent = child
+	}
+
+	last := parts[len(parts)-1]
+	if _, ok := parent.children[last]; !ok {
+		return notExist(name)
+	}
+
+	delete(parent.children, last)
+
+	return nil
+}
+
+func (fs *MemoryFileSystem) Stat(name string) (FileInfo, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	node, err := fs.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return node.info(), nil
+}
+
+func (fs *MemoryFileSystem) ReadDir(name string) ([]FileInfo, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	node, err := fs.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if !node.isDir() {
+		return nil, notDir(name)
+	}
+
+	infos := make([]FileInfo, 0, len(node.children))
+	for _, child := range node.children {
+		infos = append(infos, child.info())
+	}
+
+	return infos, nil
+}
+
+func (fs *MemoryFileSystem) Open(name string) (File, error) {
+	return fs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (fs *MemoryFileSystem) OpenFile(name string, flag int, perm uint32) (File, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	parts := fs.split(name)
+	if len(parts) == 0 {
+		return nil, errs.Wrap(errs.ErrPermission, errors.New("cannot open root"))
+	}
+
+	parent := fs.root
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := parent.children[part]
+		if !ok || !child.isDir() {
+			return nil, notExist(name)
+		}
+		parent = child
+	}
+
+	last := parts[len(parts)-1]
+	node, ok := parent.children[last]
+	if !ok {
+		if flag&os.O_CREATE == 0 {
+			return nil, notExist(name)
+		}
+		node = &memNode{name: last}
+		parent.children[last] = node
+	}
+
+	if flag&os.O_TRUNC != 0 {
+		node.data = nil
+	}
+
+	return &memFile{fs: fs, node: node}, nil
+}
+
+// memFile implements File over a *memNode, tracking its own read/write
+// offset the way an *os.File does.
+type memFile struct {
+	fs     *MemoryFileSystem
+	node   *memNode
+	offset int64
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+
+	if f.offset >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, f.node.data[f.offset:])
+	f.offset += int64(n)
+
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+
+	end := f.offset + int64(len(p))
+	if end > int64(len(f.node.data)) {
+		grown := make([]byte, end)
+		copy(grown, f.node.data)
+		f.node.data = grown
+	}
+
+	copy(f.node.data[f.offset:end], p)
+	f.offset = end
+
+	return len(p), nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case 0:
+		f.offset = offset
+	case 1:
+		f.offset += offset
+	case 2:
+		f.offset = int64(len(f.node.data)) + offset
+	}
+	return f.offset, nil
+}
+
+func (f *memFile) Close() error { return nil }
+
+func (f *memFile) Readdir(count int) ([]FileInfo, error) {
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+
+	if !f.node.isDir() {
+		return nil, notDir(f.node.name)
+	}
+
+	infos := make([]FileInfo, 0, len(f.node.children))
+	for _, child := range f.node.children {
+		infos = append(infos, child.info())
+	}
+
+	return infos, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"walk/errs"
+)
+
+func TestMemoryFileSystemWriteReadRoundTrip(t *testing.T) {
+	fs := NewMemoryFileSystem()
+
+	if err := fs.MkdirAll("a/b", 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	f, err := fs.OpenFile("a/b/c.txt", os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile (write): %v", err)
+	}
+	if _, err := f.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	f.Close()
+
+	f, err = fs.Open("a/b/c.txt")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("read %q, want %q", data, "hello")
+	}
+}
+
+func TestMemoryFileSystemReadDir(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	fs.MkdirAll("dir", 0755)
+
+	for _, name := range []string{"dir/x.txt", "dir/y.txt"} {
+		f, err := fs.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatalf("OpenFile(%s): %v", name, err)
+		}
+		f.Close()
+	}
+
+	entries, err := fs.ReadDir("dir")
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("ReadDir returned %d entries, want 2", len(entries))
+	}
+}
+
+func TestMemoryFileSystemRemove(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	f, _ := fs.OpenFile("f.txt", os.O_CREATE|os.O_WRONLY, 0644)
+	f.Close()
+
+	if err := fs.Remove("f.txt"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	if _, err := fs.Stat("f.txt"); !errs.IsNotExist(err) {
+		t.Errorf("Stat after Remove = %v, want errs.ErrNotExist", err)
+	}
+}
+
+func TestMemoryFileSystemNotExist(t *testing.T) {
+	fs := NewMemoryFileSystem()
+
+	if _, err := fs.Open("missing.txt"); !errs.IsNotExist(err) {
+		t.Errorf("Open(missing) = %v, want errs.ErrNotExist", err)
+	}
+
+	if _, err := fs.ReadDir("missing"); !errs.IsNotExist(err) {
+		t.Errorf("ReadDir(missing) = %v, want errs.ErrNotExist", err)
+	}
+}
+
+func TestMemoryFileSystemReadDirNotADirectory(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	f, _ := fs.OpenFile("f.txt", os.O_CREATE|os.O_WRONLY, 0644)
+	f.Close()
+
+	if _, err := fs.ReadDir("f.txt"); !errs.IsNotDir(err) {
+		t.Errorf("ReadDir(file) = %v, want errs.ErrNotDir", err)
+	}
+}
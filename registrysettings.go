@@ -0,0 +1,170 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package walk
+
+import (
+	"syscall"
+	"unsafe"
+
+	"walk/errs"
+)
+
+var (
+	modadvapi32 = syscall.NewLazyDLL("advapi32.dll")
+
+	procRegCreateKeyExW = modadvapi32.NewProc("RegCreateKeyExW")
+	procRegSetValueExW  = modadvapi32.NewProc("RegSetValueExW")
+	procRegEnumValueW   = modadvapi32.NewProc("RegEnumValueW")
+	procRegCloseKey     = modadvapi32.NewProc("RegCloseKey")
+)
+
+const (
+	hkeyCurrentUser      = 0x80000001
+	regOptionNonVolatile = 0
+	keyAllAccess         = 0xF003F
+	regSZ                = 1
+	errNoMoreItems       = 259
+)
+
+// RegistrySettings persists keys under HKCU\Software\<organization>\<product>
+// as REG_SZ values, one per flat key, so an application that wants
+// Windows-native config storage instead of a file doesn't need one. It
+// doesn't build dotted keys ("window.width") into a subkey tree — the
+// dot is kept as a literal character in the value name — since a single
+// flat key is plenty for the handful of values an application typically
+// persists and it avoids a chain of RegOpenKeyEx calls per key.
+type RegistrySettings struct {
+	stringMapSettings
+	organization string
+	product      string
+}
+
+func NewRegistrySettings(organization, product string) *RegistrySettings {
+	return &RegistrySettings{
+		stringMapSettings: newStringMapSettings(),
+		organization:      organization,
+		product:           product,
+	}
+}
+
+func (s *RegistrySettings) keyPath() string {
+	return `Software\` + s.organization + `\` + s.product
+}
+
+func (s *RegistrySettings) openKey() (syscall.Handle, error) {
+	keyPathUTF16, err := syscall.UTF16PtrFromString(s.keyPath())
+	if err != nil {
+		return 0, errs.Wrap(errs.ErrIO, err)
+	}
+
+	var hKey syscall.Handle
+
+	ret, _, _ := procRegCreateKeyExW.Call(
+		hkeyCurrentUser,
+		uintptr(unsafe.Pointer(keyPathUTF16)),
+		0,
+		0,
+		regOptionNonVolatile,
+		keyAllAccess,
+		0,
+		uintptr(unsafe.Pointer(&hKey)),
+		0)
+	if ret != 0 {
+		return 0, errs.Wrap(errs.ErrIO, syscall.Errno(ret))
+	}
+
+	return hKey, nil
+}
+
+func (s *RegistrySettings) Load() error {
+	hKey, err := s.openKey()
+	if err != nil {
+		return err
+	}
+	defer procRegCloseKey.Call(uintptr(hKey))
+
+	for index := uint32(0); ; index++ {
+		nameBuf := make([]uint16, 256)
+		nameLen := uint32(len(nameBuf))
+		var valueType uint32
+		dataBuf := make([]byte, 4096)
+		dataLen := uint32(len(dataBuf))
+
+		ret, _, _ := procRegEnumValueW.Call(
+			uintptr(hKey),
+			uintptr(index),
+			uintptr(unsafe.Pointer(&nameBuf[0])),
+			uintptr(unsafe.Pointer(&nameLen)),
+			0,
+			uintptr(unsafe.Pointer(&valueType)),
+			uintptr(unsafe.Pointer(&dataBuf[0])),
+			uintptr(unsafe.Pointer(&dataLen)))
+
+		if ret == errNoMoreItems {
+			break
+		}
+		if ret != 0 {
+			return errs.Wrap(errs.ErrIO, syscall.Errno(ret))
+		}
+
+		if valueType != regSZ {
+			continue
+		}
+
+		name := syscall.UTF16ToString(nameBuf[:nameLen])
+		value := syscall.UTF16ToString(utf16FromBytes(dataBuf[:dataLen]))
+
+		s.set(name, value)
+	}
+
+	return nil
+}
+
+func (s *RegistrySettings) Save() error {
+	hKey, err := s.openKey()
+	if err != nil {
+		return err
+	}
+	defer procRegCloseKey.Call(uintptr(hKey))
+
+	for _, key := range s.Keys() {
+		val, _ := s.Get(key)
+
+		nameUTF16, err := syscall.UTF16PtrFromString(key)
+		if err != nil {
+			return errs.Wrap(errs.ErrIO, err)
+		}
+
+		valueUTF16, err := syscall.UTF16FromString(val)
+		if err != nil {
+			return errs.Wrap(errs.ErrIO, err)
+		}
+
+		ret, _, _ := procRegSetValueExW.Call(
+			uintptr(hKey),
+			uintptr(unsafe.Pointer(nameUTF16)),
+			0,
+			regSZ,
+			uintptr(unsafe.Pointer(&valueUTF16[0])),
+			uintptr(len(valueUTF16)*2))
+		if ret != 0 {
+			return errs.Wrap(errs.ErrIO, syscall.Errno(ret))
+		}
+	}
+
+	return nil
+}
+
+// utf16FromBytes reinterprets a REG_SZ value's raw little-endian UTF-16
+// bytes (as returned by RegEnumValueW) as a []uint16 for UTF16ToString.
+func utf16FromBytes(b []byte) []uint16 {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = uint16(b[i*2]) | uint16(b[i*2+1])<<8
+	}
+	return u
+}
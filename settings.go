@@ -0,0 +1,190 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+
+	"walk/errs"
+)
+
+// Settings is implemented by every persistence backend walk ships
+// (IniFileSettings, JSONFileSettings, TOMLFileSettings, RegistrySettings,
+// MemorySettings) and by anything an application wants to plug in
+// instead. Keys are flat strings, but backends that have a native notion
+// of sections (ini headers, TOML tables, nested JSON objects) map a
+// "section.key" dotted key to and from their own structure.
+// RegistrySettings keeps the dotted key as a single value name.
+type Settings interface {
+	Get(key string) (string, bool)
+	Put(key, value string) error
+
+	GetBool(key string) (value, ok bool)
+	PutBool(key string, value bool) error
+
+	GetInt(key string) (value int, ok bool)
+	PutInt(key string, value int) error
+
+	// Keys returns every key currently set, sorted.
+	Keys() []string
+
+	Load() error
+	Save() error
+
+	// Changed is published, with the key that changed, whenever Put,
+	// PutBool or PutInt succeeds, or Load sets a key's value.
+	Changed() *StringEvent
+}
+
+// stringMapSettings implements the typed accessors, Keys and change
+// notification shared by every flat, string-keyed Settings backend.
+// Concrete types embed it and only need to provide Load and Save (and,
+// if their format needs it, their own Put to validate before storing).
+type stringMapSettings struct {
+	data             map[string]string
+	changedPublisher StringEventPublisher
+}
+
+func newStringMapSettings() stringMapSettings {
+	return stringMapSettings{data: make(map[string]string)}
+}
+
+func (s *stringMapSettings) Get(key string) (string, bool) {
+	val, ok := s.data[key]
+	return val, ok
+}
+
+func (s *stringMapSettings) Put(key, value string) error {
+	s.set(key, value)
+	return nil
+}
+
+// set stores value under key and publishes Changed, bypassing whatever
+// validation an embedding type's own Put adds. It's what Load uses to
+// populate data, and what a shadowing Put delegates to once it's happy
+// with key and value.
+func (s *stringMapSettings) set(key, value string) {
+	s.data[key] = value
+	s.changedPublisher.Publish(key)
+}
+
+func (s *stringMapSettings) GetBool(key string) (bool, bool) {
+	val, ok := s.data[key]
+	if !ok {
+		return false, false
+	}
+
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, false
+	}
+
+	return b, true
+}
+
+func (s *stringMapSettings) PutBool(key string, value bool) error {
+	return s.Put(key, strconv.FormatBool(value))
+}
+
+func (s *stringMapSettings) GetInt(key string) (int, bool) {
+	val, ok := s.data[key]
+	if !ok {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, false
+	}
+
+	return n, true
+}
+
+func (s *stringMapSettings) PutInt(key string, value int) error {
+	return s.Put(key, strconv.Itoa(value))
+}
+
+func (s *stringMapSettings) Keys() []string {
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (s *stringMapSettings) Changed() *StringEvent {
+	return s.changedPublisher.Event()
+}
+
+// MemorySettings is an in-process Settings backend with no persistence:
+// Load and Save are no-ops. It exists for tests and for callers that
+// want the Settings interface without touching disk or the registry.
+type MemorySettings struct {
+	stringMapSettings
+}
+
+func NewMemorySettings() *MemorySettings {
+	return &MemorySettings{stringMapSettings: newStringMapSettings()}
+}
+
+func (s *MemorySettings) Load() error { return nil }
+func (s *MemorySettings) Save() error { return nil }
+
+// The read/write helpers below are shared by the whole-file backends
+// (JSONFileSettings, TOMLFileSettings) that don't need IniFileSettings'
+// line-by-line, comment-preserving handling; settingsFileExists is used by
+// IniFileSettings as well.
+
+func settingsFileExists(fs FileSystem, filePath string) (bool, error) {
+	_, err := fs.Stat(filePath)
+	if err != nil {
+		if errs.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func readSettingsFile(fs FileSystem, filePath string) ([]byte, error) {
+	file, err := fs.OpenFile(filePath, os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrIO, err)
+	}
+
+	return data, nil
+}
+
+func writeSettingsFile(fs FileSystem, filePath string, data []byte) error {
+	dirPath, _ := path.Split(filePath)
+	if err := fs.MkdirAll(dirPath, 0644); err != nil {
+		return err
+	}
+
+	file, err := fs.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return errs.Wrap(errs.ErrIO, err)
+	}
+
+	return nil
+}
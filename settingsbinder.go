@@ -0,0 +1,183 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// SettingsBinder wires a widget's persistent state — a window's bounds,
+// a Splitter's pane sizes, a TreeView's expanded nodes — to a Settings
+// implementation, so restoring it on startup and keeping it saved as the
+// user resizes or expands things is one Bind* call per widget instead of
+// hand-rolled change handlers in every application. Callers are expected
+// to have already called settings.Load before binding, so the first
+// restore sees whatever was on disk.
+type SettingsBinder struct {
+	settings Settings
+
+	// SaveFailed, if set, is called whenever a save triggered by a widget
+	// change event fails - e.g. because the Settings implementation
+	// rejected the value. There's no caller to return the error to at that
+	// point, so it's reported this way instead of being discarded, the
+	// same way DirWalker reports errors found on its own background
+	// goroutines through EntryFailed rather than a return value.
+	SaveFailed func(error)
+}
+
+func NewSettingsBinder(settings Settings) *SettingsBinder {
+	return &SettingsBinder{settings: settings}
+}
+
+// BindWindowBounds restores key's saved bounds onto window, if present,
+// and saves window's bounds under key every time they change.
+func (b *SettingsBinder) BindWindowBounds(key string, window Form) {
+	if rect, ok := b.restoreRect(key); ok {
+		window.SetBounds(rect)
+	}
+
+	window.BoundsChanged().Attach(func() {
+		b.saveRect(key, window.Bounds())
+	})
+}
+
+// BindSplitterSizes restores key's saved pane sizes onto splitter, if
+// present and its pane count hasn't changed, and saves the sizes every
+// time the user drags a handle.
+func (b *SettingsBinder) BindSplitterSizes(key string, splitter *Splitter) {
+	if sizes, ok := b.restoreInts(key); ok && len(sizes) == splitter.Children().Len() {
+		splitter.SetSizes(sizes)
+	}
+
+	splitter.SizesChanged().Attach(func() {
+		b.saveInts(key, splitter.Sizes())
+	})
+}
+
+// treeViewPathSep joins the encoded paths BindTreeViewExpansion stores
+// under a single key. It's a control character rather than something like
+// "," so it can never collide with an actual path component, and
+// url.QueryEscape - which is what encodes each path - never produces it.
+const treeViewPathSep = "\x1f"
+
+// BindTreeViewExpansion restores, under key, which nodes of treeView
+// were expanded — identified by the "/"-joined path of their Text(), the
+// same identity populateTreeViewItem's lazy loading relies on — and
+// keeps that set up to date as the user expands or collapses nodes.
+//
+// The expanded set is stored as each path's query-escaped form joined by
+// treeViewPathSep: Settings implementations like IniFileSettings reject
+// values containing a raw "\n" or "\r", which a multi-segment path joined
+// by "\n" would otherwise be prone to produce.
+func (b *SettingsBinder) BindTreeViewExpansion(key string, treeView *TreeView) {
+	if raw, ok := b.settings.Get(key); ok {
+		expanded := make(map[string]bool)
+		for _, p := range strings.Split(raw, treeViewPathSep) {
+			if p == "" {
+				continue
+			}
+			if decoded, err := url.QueryUnescape(p); err == nil {
+				expanded[decoded] = true
+			}
+		}
+
+		walkTreeViewItems(treeView, func(item *TreeViewItem) {
+			if expanded[treeViewItemPath(item)] {
+				item.SetExpanded(true)
+			}
+		})
+	}
+
+	save := func() {
+		var paths []string
+		walkTreeViewItems(treeView, func(item *TreeViewItem) {
+			if item.Expanded() {
+				paths = append(paths, url.QueryEscape(treeViewItemPath(item)))
+			}
+		})
+		b.reportError(b.settings.Put(key, strings.Join(paths, treeViewPathSep)))
+	}
+
+	treeView.ItemExpanded().Attach(func(item *TreeViewItem) { save() })
+	treeView.ItemCollapsed().Attach(func(item *TreeViewItem) { save() })
+}
+
+// reportError forwards a non-nil save error to SaveFailed, if one is set.
+func (b *SettingsBinder) reportError(err error) {
+	if err != nil && b.SaveFailed != nil {
+		b.SaveFailed(err)
+	}
+}
+
+func treeViewItemPath(item *TreeViewItem) string {
+	var parts []string
+	for item != nil {
+		parts = append([]string{item.Text()}, parts...)
+		item = item.Parent()
+	}
+	return path.Join(parts...)
+}
+
+func walkTreeViewItems(treeView *TreeView, f func(item *TreeViewItem)) {
+	var visit func(items *TreeViewItemList)
+	visit = func(items *TreeViewItemList) {
+		for i := 0; i < items.Len(); i++ {
+			item := items.At(i)
+			f(item)
+			visit(item.Children())
+		}
+	}
+	visit(treeView.Items())
+}
+
+func (b *SettingsBinder) restoreRect(key string) (Rectangle, bool) {
+	raw, ok := b.settings.Get(key)
+	if !ok {
+		return Rectangle{}, false
+	}
+
+	var rect Rectangle
+	if _, err := fmt.Sscanf(raw, "%d,%d,%d,%d", &rect.X, &rect.Y, &rect.Width, &rect.Height); err != nil {
+		return Rectangle{}, false
+	}
+
+	return rect, true
+}
+
+func (b *SettingsBinder) saveRect(key string, rect Rectangle) {
+	b.reportError(b.settings.Put(key, fmt.Sprintf("%d,%d,%d,%d", rect.X, rect.Y, rect.Width, rect.Height)))
+}
+
+func (b *SettingsBinder) restoreInts(key string) ([]int, bool) {
+	raw, ok := b.settings.Get(key)
+	if !ok || raw == "" {
+		return nil, false
+	}
+
+	parts := strings.Split(raw, ",")
+	sizes := make([]int, len(parts))
+
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		sizes[i] = n
+	}
+
+	return sizes, true
+}
+
+func (b *SettingsBinder) saveInts(key string, sizes []int) {
+	parts := make([]string, len(sizes))
+	for i, n := range sizes {
+		parts[i] = strconv.Itoa(n)
+	}
+	b.reportError(b.settings.Put(key, strings.Join(parts, ",")))
+}
@@ -0,0 +1,183 @@
+// Copyright 2011 The Walk Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package walk
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"walk/errs"
+)
+
+// TOMLFileSettings is a Settings backend storing key/value pairs in a
+// TOML document, with "[section]" tables mapped to "section.key" in the
+// flat key space Settings exposes, the same way IniFileSettings maps ini
+// sections. Only what walk itself needs is supported: top-level tables
+// (no nested "[a.b]" tables, no arrays, no inline tables, no dates) —
+// plenty for application settings, not a general TOML parser.
+type TOMLFileSettings struct {
+	stringMapSettings
+	fs   FileSystem
+	path string
+
+	// kinds remembers, per flat key, whether the value was read as a
+	// bare (unquoted) number or bool, so Save writes it back the same
+	// way instead of quoting it as a string.
+	kinds map[string]byte
+}
+
+func NewTOMLFileSettings(path string) *TOMLFileSettings {
+	return &TOMLFileSettings{
+		stringMapSettings: newStringMapSettings(),
+		fs:                NewOSFileSystem(),
+		path:              path,
+		kinds:             make(map[string]byte),
+	}
+}
+
+// SetFileSystem overrides the FileSystem path is read from and written
+// to. It defaults to an OSFileSystem rooted at the OS root.
+func (s *TOMLFileSettings) SetFileSystem(fs FileSystem) {
+	s.fs = fs
+}
+
+func (s *TOMLFileSettings) Load() error {
+	exists, err := settingsFileExists(s.fs, s.path)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	data, err := readSettingsFile(s.fs, s.path)
+	if err != nil {
+		return err
+	}
+
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		trimmed := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+
+		case strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]"):
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+
+		default:
+			assignIndex := strings.Index(trimmed, "=")
+			if assignIndex == -1 {
+				return errs.Wrap(errs.ErrInvalidFormat, fmt.Errorf("bad line format: missing '=': %q", trimmed))
+			}
+
+			key := strings.TrimSpace(trimmed[:assignIndex])
+			rawVal := strings.TrimSpace(trimmed[assignIndex+1:])
+			flat := iniFlatKey(section, key)
+
+			val, kind, err := parseTOMLValue(rawVal)
+			if err != nil {
+				return errs.Wrap(errs.ErrInvalidFormat, err)
+			}
+
+			if kind != 0 {
+				s.kinds[flat] = kind
+			}
+			s.set(flat, val)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return errs.Wrap(errs.ErrIO, err)
+	}
+
+	return nil
+}
+
+// parseTOMLValue decodes one TOML scalar: a double-quoted string, a bare
+// true/false, or a bare integer/float. It returns the decoded value as a
+// string plus a kind byte ('b' bool, 'n' number, 0 for a plain string)
+// for Save to round-trip the literal form.
+func parseTOMLValue(raw string) (value string, kind byte, err error) {
+	switch {
+	case len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"':
+		unquoted, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", 0, err
+		}
+		return unquoted, 0, nil
+
+	case raw == "true" || raw == "false":
+		return raw, 'b', nil
+
+	default:
+		if _, err := strconv.ParseFloat(raw, 64); err == nil {
+			return raw, 'n', nil
+		}
+		return "", 0, fmt.Errorf("unsupported TOML value: %q", raw)
+	}
+}
+
+// PutBool stores value and records it as a TOML bool kind, so Save writes
+// it back bare instead of falling through to stringMapSettings.PutBool's
+// quoted "true"/"false" - kinds otherwise only gets populated for a key
+// that's been through Load, which a fresh, never loaded TOMLFileSettings
+// never has.
+func (s *TOMLFileSettings) PutBool(key string, value bool) error {
+	s.kinds[key] = 'b'
+	return s.stringMapSettings.PutBool(key, value)
+}
+
+// PutInt stores value and records it as a TOML number kind, for the same
+// reason PutBool does.
+func (s *TOMLFileSettings) PutInt(key string, value int) error {
+	s.kinds[key] = 'n'
+	return s.stringMapSettings.PutInt(key, value)
+}
+
+func (s *TOMLFileSettings) Save() error {
+	var sectionOrder []string
+	bySection := make(map[string][]string)
+
+	for _, flat := range s.Keys() {
+		section, key := iniSplitFlatKey(flat)
+		if _, ok := bySection[section]; !ok {
+			sectionOrder = append(sectionOrder, section)
+		}
+		bySection[section] = append(bySection[section], key)
+	}
+
+	var buf bytes.Buffer
+
+	for _, section := range sectionOrder {
+		if section != "" {
+			fmt.Fprintf(&buf, "[%s]\n", section)
+		}
+
+		for _, key := range bySection[section] {
+			flat := iniFlatKey(section, key)
+			val, _ := s.Get(flat)
+			fmt.Fprintf(&buf, "%s = %s\n", key, formatTOMLValue(val, s.kinds[flat]))
+		}
+	}
+
+	return writeSettingsFile(s.fs, s.path, buf.Bytes())
+}
+
+func formatTOMLValue(val string, kind byte) string {
+	switch kind {
+	case 'b', 'n':
+		return val
+	default:
+		return strconv.Quote(val)
+	}
+}